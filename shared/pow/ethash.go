@@ -0,0 +1,290 @@
+package pow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/sha3"
+)
+
+// Ethash constants, the sizes of the cache and the dataset are configurable instead
+// of growing with the epoch.
+const (
+	hashBytes             = 64
+	hashWords             = hashBytes / 4
+	mixBytes              = 128
+	mixWords              = mixBytes / 4
+	cacheRounds           = 3
+	datasetParents        = 256
+	loopAccesses          = 64
+	keepEpochs            = 2
+	fnvPrime       uint32 = 0x01000193
+)
+
+// Epoched is an algorithm whose parameters rotate with time. A solution is
+// verified at the epoch its challenge was issued in.
+type Epoched interface {
+	Algorithm
+	Epoch() uint64
+	At(epoch uint64) Algorithm
+}
+
+// Ethash is the hashimoto loop over a dataset derived from the epoch seed.
+// Every hash reads random dataset items, so mining needs the whole dataset in memory,
+// while a light instance verifies by computing the few items it reads from the cache.
+type Ethash struct {
+	cacheSize   int
+	datasetSize int
+	epochLength time.Duration
+	full        bool
+	now         func() time.Time
+
+	mu       sync.Mutex
+	caches   map[uint64]*epochData
+	datasets map[uint64]*epochData
+}
+
+// epochData is generated once, by the first caller that needs it.
+type epochData struct {
+	once sync.Once
+	data []uint32
+}
+
+// NewEthash returns a light instance that verifies from the cache or a full one that
+// mines with the dataset. Sizes are in bytes, epochs last epochLength.
+func NewEthash(cacheSize, datasetSize int, epochLength time.Duration, full bool) (*Ethash, error) {
+	if cacheSize < hashBytes || cacheSize%hashBytes != 0 {
+		return nil, fmt.Errorf("invalid ethash cache size %d, must be a multiple of %d", cacheSize, hashBytes)
+	}
+	if datasetSize < mixBytes || datasetSize%mixBytes != 0 {
+		return nil, fmt.Errorf("invalid ethash dataset size %d, must be a multiple of %d", datasetSize, mixBytes)
+	}
+	return &Ethash{
+		cacheSize:   cacheSize,
+		datasetSize: datasetSize,
+		epochLength: epochLength,
+		full:        full,
+		now:         time.Now,
+		caches:      make(map[uint64]*epochData),
+		datasets:    make(map[uint64]*epochData),
+	}, nil
+}
+
+func (e *Ethash) Name() string {
+	return AlgorithmEthash
+}
+
+// Epoch is the current epoch, counted from the Unix epoch.
+func (e *Ethash) Epoch() uint64 {
+	if e.epochLength <= 0 {
+		return 0
+	}
+	return uint64(e.now().UnixNano() / int64(e.epochLength))
+}
+
+func (e *Ethash) Params() *Params {
+	return &Params{Epoch: e.Epoch(), CacheSize: e.cacheSize, DatasetSize: e.datasetSize}
+}
+
+func (e *Ethash) Hash(challenge string, nonce uint64) []byte {
+	return e.At(e.Epoch()).Hash(challenge, nonce)
+}
+
+// At returns the algorithm fixed at the epoch, generating its cache and, for a full
+// instance, its dataset. Only the latest epochs are kept.
+func (e *Ethash) At(epoch uint64) Algorithm {
+	cache := e.load(e.caches, epoch, func() []uint32 {
+		return generateCache(e.cacheSize, seedHash(epoch))
+	})
+	at := ethashEpoch{Ethash: e, epoch: epoch, cache: cache}
+	if e.full {
+		at.dataset = e.load(e.datasets, epoch, func() []uint32 {
+			return generateDataset(e.datasetSize, cache)
+		})
+	}
+	return at
+}
+
+// load returns the data of the epoch, generating it outside mu: callers of the same
+// epoch wait for the generation, callers of the others don't.
+func (e *Ethash) load(m map[uint64]*epochData, epoch uint64, generate func() []uint32) []uint32 {
+	e.mu.Lock()
+	d, ok := m[epoch]
+	if !ok {
+		d = &epochData{}
+		keep(m, epoch, d)
+	}
+	e.mu.Unlock()
+
+	d.once.Do(func() { d.data = generate() })
+	return d.data
+}
+
+// ethashEpoch is Ethash at a fixed epoch.
+type ethashEpoch struct {
+	*Ethash
+	epoch   uint64
+	cache   []uint32
+	dataset []uint32
+}
+
+func (e ethashEpoch) Params() *Params {
+	return &Params{Epoch: e.epoch, CacheSize: e.cacheSize, DatasetSize: e.datasetSize}
+}
+
+func (e ethashEpoch) Hash(challenge string, nonce uint64) []byte {
+	lookup := func(index uint32) []uint32 {
+		return datasetItem(e.cache, index)
+	}
+	if e.dataset != nil {
+		lookup = func(index uint32) []uint32 {
+			return e.dataset[index*hashWords : (index+1)*hashWords]
+		}
+	}
+	return hashimoto(crypto.Keccak256([]byte(challenge)), nonce, uint32(e.datasetSize/mixBytes), lookup)
+}
+
+// keep adds the epoch to m, dropping the oldest ones.
+func keep(m map[uint64]*epochData, epoch uint64, data *epochData) {
+	m[epoch] = data
+	for len(m) > keepEpochs {
+		oldest := epoch
+		for e := range m {
+			oldest = min(oldest, e)
+		}
+		delete(m, oldest)
+	}
+}
+
+func seedHash(epoch uint64) []byte {
+	return crypto.Keccak256(binary.BigEndian.AppendUint64(nil, epoch))
+}
+
+// generateCache fills the cache with sequential Keccak-512 hashes of the seed and
+// mixes it with rounds of RandMemoHash.
+func generateCache(size int, seed []byte) []uint32 {
+	n := size / hashBytes
+	hasher := newKeccak512()
+	cache := make([]byte, size)
+
+	hasher(cache[:hashBytes], seed)
+	for i := 1; i < n; i++ {
+		hasher(cache[i*hashBytes:(i+1)*hashBytes], cache[(i-1)*hashBytes:i*hashBytes])
+	}
+
+	temp := make([]byte, hashBytes)
+	for round := 0; round < cacheRounds; round++ {
+		for i := 0; i < n; i++ {
+			src := ((i - 1 + n) % n) * hashBytes
+			xor := int(binary.LittleEndian.Uint32(cache[i*hashBytes:]) % uint32(n) * hashBytes)
+			for j := range temp {
+				temp[j] = cache[src+j] ^ cache[xor+j]
+			}
+			hasher(cache[i*hashBytes:(i+1)*hashBytes], temp)
+		}
+	}
+	return toWords(cache)
+}
+
+// generateDataset computes every dataset item from the cache on all CPUs.
+func generateDataset(size int, cache []uint32) []uint32 {
+	items := uint32(size / hashBytes)
+	dataset := make([]uint32, items*hashWords)
+	workers := uint32(runtime.NumCPU())
+
+	var wg sync.WaitGroup
+	for w := uint32(0); w < workers; w++ {
+		wg.Add(1)
+		go func(w uint32) {
+			defer wg.Done()
+			for i := w; i < items; i += workers {
+				copy(dataset[i*hashWords:], datasetItem(cache, i))
+			}
+		}(w)
+	}
+	wg.Wait()
+	return dataset
+}
+
+// datasetItem mixes datasetParents pseudo-random cache rows into one dataset item.
+func datasetItem(cache []uint32, index uint32) []uint32 {
+	rows := uint32(len(cache) / hashWords)
+	hasher := newKeccak512()
+
+	mix := make([]uint32, hashWords)
+	copy(mix, cache[(index%rows)*hashWords:])
+	mix[0] ^= index
+	mix = keccak512Words(hasher, mix)
+
+	for i := uint32(0); i < datasetParents; i++ {
+		parent := fnv(index^i, mix[i%hashWords]) % rows
+		for j := range mix {
+			mix[j] = fnv(mix[j], cache[parent*hashWords+uint32(j)])
+		}
+	}
+	return keccak512Words(hasher, mix)
+}
+
+// hashimoto mixes loopAccesses random pairs of dataset items into the seed of the
+// header and nonce.
+func hashimoto(header []byte, nonce uint64, rows uint32, lookup func(index uint32) []uint32) []byte {
+	seed := make([]byte, hashBytes)
+	newKeccak512()(seed, binary.LittleEndian.AppendUint64(append([]byte{}, header...), nonce))
+	seedHead := binary.LittleEndian.Uint32(seed)
+
+	mix := make([]uint32, mixWords)
+	for i := range mix {
+		mix[i] = binary.LittleEndian.Uint32(seed[i%hashWords*4:])
+	}
+	temp := make([]uint32, mixWords)
+	for i := uint32(0); i < loopAccesses; i++ {
+		parent := fnv(i^seedHead, mix[i%mixWords]) % rows
+		for j := uint32(0); j < mixBytes/hashBytes; j++ {
+			copy(temp[j*hashWords:], lookup(2*parent+j))
+		}
+		for j := range mix {
+			mix[j] = fnv(mix[j], temp[j])
+		}
+	}
+
+	digest := make([]byte, 0, mixWords)
+	for i := 0; i < mixWords; i += 4 {
+		digest = binary.LittleEndian.AppendUint32(digest, fnv(fnv(fnv(mix[i], mix[i+1]), mix[i+2]), mix[i+3]))
+	}
+	return crypto.Keccak256(seed, digest)
+}
+
+func fnv(a, b uint32) uint32 {
+	return a*fnvPrime ^ b
+}
+
+// newKeccak512 returns a function writing the Keccak-512 of data to dest.
+func newKeccak512() func(dest, data []byte) {
+	h := sha3.NewLegacyKeccak512()
+	return func(dest, data []byte) {
+		h.Reset()
+		h.Write(data)
+		h.Sum(dest[:0])
+	}
+}
+
+func keccak512Words(hasher func(dest, data []byte), words []uint32) []uint32 {
+	buf := make([]byte, hashBytes)
+	for i, w := range words {
+		binary.LittleEndian.PutUint32(buf[i*4:], w)
+	}
+	hasher(buf, buf)
+	return toWords(buf)
+}
+
+func toWords(b []byte) []uint32 {
+	words := make([]uint32, len(b)/4)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	return words
+}
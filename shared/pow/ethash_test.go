@@ -0,0 +1,147 @@
+package pow
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+)
+
+const (
+	testCacheSize   = 1024
+	testDatasetSize = 32 * 1024
+)
+
+func newTestEthash(t *testing.T, now *time.Time, full bool) *Ethash {
+	t.Helper()
+	e, err := NewEthash(testCacheSize, testDatasetSize, time.Hour, full)
+	if err != nil {
+		t.Fatalf("NewEthash() error = %v", err)
+	}
+	e.now = func() time.Time { return *now }
+	return e
+}
+
+func TestNewEthash(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		cacheSize   int
+		datasetSize int
+		wantErr     bool
+	}{
+		{
+			name:        "Valid sizes",
+			cacheSize:   testCacheSize,
+			datasetSize: testDatasetSize,
+		},
+		{
+			name:        "Cache size not a multiple of 64",
+			cacheSize:   1000,
+			datasetSize: testDatasetSize,
+			wantErr:     true,
+		},
+		{
+			name:        "Dataset size not a multiple of 128",
+			cacheSize:   testCacheSize,
+			datasetSize: 64,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEthash(tt.cacheSize, tt.datasetSize, time.Hour, false); (err != nil) != tt.wantErr {
+				t.Errorf("NewEthash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEthash_lightMatchesFull(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	light := newTestEthash(t, &now, false)
+	full := newTestEthash(t, &now, true)
+
+	for nonce := uint64(0); nonce < 16; nonce++ {
+		if !bytes.Equal(light.Hash("challenge", nonce), full.Hash("challenge", nonce)) {
+			t.Fatalf("light and full hashes of nonce %d differ", nonce)
+		}
+	}
+}
+
+func TestEthash_mine(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	light := newTestEthash(t, &now, false)
+	full := newTestEthash(t, &now, true).At(light.Epoch())
+
+	target := new(big.Int).Lsh(big.NewInt(1), 256-8)
+	var nonce uint64
+	for new(big.Int).SetBytes(full.Hash("challenge", nonce)).Cmp(target) != -1 {
+		nonce++
+	}
+
+	// the challenge is verified at its epoch after the rotation
+	now = now.Add(time.Hour)
+	if new(big.Int).SetBytes(light.At(light.Epoch()-1).Hash("challenge", nonce)).Cmp(target) != -1 {
+		t.Error("light verification rejected the mined nonce")
+	}
+	if bytes.Equal(light.Hash("challenge", nonce), full.Hash("challenge", nonce)) {
+		t.Error("hash didn't change with the epoch")
+	}
+}
+
+func TestEthash_epochs(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := newTestEthash(t, &now, false)
+
+	epoch := e.Epoch()
+	want := &Params{Epoch: epoch, CacheSize: testCacheSize, DatasetSize: testDatasetSize}
+	if got := e.Params(); *got != *want {
+		t.Errorf("Ethash.Params() = %+v, want %+v", got, want)
+	}
+
+	now = now.Add(59 * time.Minute)
+	if got := e.Epoch(); got != epoch {
+		t.Errorf("Ethash.Epoch() within the hour = %d, want %d", got, epoch)
+	}
+	now = now.Add(time.Minute)
+	if got := e.Epoch(); got != epoch+1 {
+		t.Errorf("Ethash.Epoch() after the hour = %d, want %d", got, epoch+1)
+	}
+
+	for i := uint64(0); i < 4; i++ {
+		e.At(epoch + i)
+	}
+	if len(e.caches) != keepEpochs {
+		t.Errorf("caches kept = %d, want %d", len(e.caches), keepEpochs)
+	}
+	if _, ok := e.caches[epoch+3]; !ok {
+		t.Error("latest epoch cache was dropped")
+	}
+}
+
+func TestEthash_At_concurrent(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := newTestEthash(t, &now, true)
+
+	// every caller of the epoch gets the dataset generated once
+	datasets := make(chan []uint32, 8)
+	for i := 0; i < cap(datasets); i++ {
+		go func() { datasets <- e.At(e.Epoch()).(ethashEpoch).dataset }()
+	}
+	first := <-datasets
+	for i := 1; i < cap(datasets); i++ {
+		if dataset := <-datasets; &dataset[0] != &first[0] {
+			t.Fatal("Ethash.At() generated the dataset more than once")
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package pow
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	stampVersion = "1"
+	stampFields  = 7
+	randBytes    = 12
+)
+
+// Layouts of the stamp date: the day, the minute or the second, always in UTC.
+var stampDates = []struct {
+	layout    string
+	precision time.Duration
+}{
+	{layout: "060102", precision: 24 * time.Hour},
+	{layout: "0601021504", precision: time.Minute},
+	{layout: "060102150405", precision: time.Second},
+}
+
+var (
+	ErrStampMalformed = errors.New("malformed hashcash stamp")
+	ErrStampBits      = errors.New("hashcash stamp claims too few bits")
+	ErrStampDate      = errors.New("hashcash stamp is out of date")
+	ErrStampResource  = errors.New("hashcash stamp is for another resource")
+	ErrStampHash      = errors.New("hashcash stamp hash doesn't have the claimed bits")
+)
+
+// Stamper is an algorithm solved with a stamp instead of a nonce. The resource of
+// its challenge is the request id.
+type Stamper interface {
+	Algorithm
+	Verify(stamp, resource string, bits int, now time.Time) error
+}
+
+// Stamp is a Hashcash version 1 stamp, ver:bits:date:resource:ext:rand:counter.
+type Stamp struct {
+	Bits     int
+	Date     string
+	Resource string
+	Ext      string
+	Rand     string
+	Counter  string
+}
+
+// NewStamp starts a stamp for the resource dated now with a random rand field,
+// it is solved by finding the counter.
+func NewStamp(resource string, bits int, now time.Time) Stamp {
+	r := make([]byte, randBytes)
+	_, _ = rand.Read(r)
+	return Stamp{
+		Bits:     bits,
+		Date:     now.UTC().Format(stampDates[len(stampDates)-1].layout),
+		Resource: resource,
+		Rand:     base64.StdEncoding.EncodeToString(r),
+	}
+}
+
+// IsStamp tells a stamp from a nonce.
+func IsStamp(s string) bool {
+	return strings.HasPrefix(s, stampVersion+":")
+}
+
+func ParseStamp(s string) (Stamp, error) {
+	fields := strings.Split(s, ":")
+	if len(fields) != stampFields || fields[0] != stampVersion {
+		return Stamp{}, ErrStampMalformed
+	}
+	bits, err := strconv.Atoi(fields[1])
+	if err != nil || bits < 0 {
+		return Stamp{}, ErrStampMalformed
+	}
+	return Stamp{
+		Bits:     bits,
+		Date:     fields[2],
+		Resource: fields[3],
+		Ext:      fields[4],
+		Rand:     fields[5],
+		Counter:  fields[6],
+	}, nil
+}
+
+// Prefix is the stamp up to the counter.
+func (s Stamp) Prefix() string {
+	return strings.Join([]string{stampVersion, strconv.Itoa(s.Bits), s.Date, s.Resource, s.Ext, s.Rand, ""}, ":")
+}
+
+func (s Stamp) String() string {
+	return s.Prefix() + s.Counter
+}
+
+// Time is the start of the date and how long the date lasts.
+func (s Stamp) Time() (time.Time, time.Duration, error) {
+	for _, d := range stampDates {
+		if len(s.Date) == len(d.layout) {
+			t, err := time.Parse(d.layout, s.Date)
+			if err != nil {
+				return time.Time{}, 0, ErrStampMalformed
+			}
+			return t, d.precision, nil
+		}
+	}
+	return time.Time{}, 0, ErrStampMalformed
+}
+
+// Hashcash is the Hashcash stamp format with SHA-1, as the original tool, or SHA-256.
+// Stamps are valid within Window of the time they are verified at.
+type Hashcash struct {
+	Hasher string
+	Window time.Duration
+}
+
+func NewHashcash(hasher string, window time.Duration) (Hashcash, error) {
+	switch hasher {
+	case "":
+		hasher = HashSHA1
+	case HashSHA1, HashSHA256:
+	default:
+		return Hashcash{}, fmt.Errorf("unknown hashcash hash '%s'", hasher)
+	}
+	return Hashcash{Hasher: hasher, Window: window}, nil
+}
+
+func (h Hashcash) Name() string {
+	return AlgorithmHashcash
+}
+
+func (h Hashcash) Params() *Params {
+	return &Params{Hash: h.Hasher}
+}
+
+// Hash hashes the stamp with the challenge as its prefix and the nonce as its counter.
+func (h Hashcash) Hash(challenge string, nonce uint64) []byte {
+	return h.sum(strconv.AppendUint([]byte(challenge), nonce, 10))
+}
+
+// Verify checks that the stamp is for the resource, claims at least bits, is dated
+// within the window and its hash has as many leading zero bits as it claims.
+func (h Hashcash) Verify(stamp, resource string, bits int, now time.Time) error {
+	s, err := ParseStamp(stamp)
+	if err != nil {
+		return err
+	}
+	date, precision, err := s.Time()
+	if err != nil {
+		return err
+	}
+	if s.Resource != resource {
+		return ErrStampResource
+	}
+	if s.Bits < bits {
+		return ErrStampBits
+	}
+	if date.After(now.Add(h.Window)) || date.Add(precision).Before(now.Add(-h.Window)) {
+		return ErrStampDate
+	}
+	if LeadingZeros(h.sum([]byte(stamp))) < s.Bits {
+		return ErrStampHash
+	}
+	return nil
+}
+
+func (h Hashcash) sum(data []byte) []byte {
+	if h.Hasher == HashSHA256 {
+		sum := sha256.Sum256(data)
+		return sum[:]
+	}
+	sum := sha1.Sum(data)
+	return sum[:]
+}
+
+// LeadingZeros counts the leading zero bits of the hash.
+func LeadingZeros(hash []byte) int {
+	n := 0
+	for _, b := range hash {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,199 @@
+package pow
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// mineStamp finds the counter of the stamp the way a client does.
+func mineStamp(h Hashcash, s Stamp) string {
+	prefix := s.Prefix()
+	var nonce uint64
+	for LeadingZeros(h.Hash(prefix, nonce)) < s.Bits {
+		nonce++
+	}
+	s.Counter = strconv.FormatUint(nonce, 10)
+	return s.String()
+}
+
+func TestParseStamp(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		stamp   string
+		want    Stamp
+		wantErr bool
+	}{
+		{
+			name:  "Stamp of the hashcash tool",
+			stamp: "1:20:060408:adam@cypherspace.org::1QTjaYd7niiQA/sc:ePa",
+			want:  Stamp{Bits: 20, Date: "060408", Resource: "adam@cypherspace.org", Rand: "1QTjaYd7niiQA/sc", Counter: "ePa"},
+		},
+		{
+			name:  "Stamp with extension",
+			stamp: "1:8:260101120000:uid:ext=1:rand:42",
+			want:  Stamp{Bits: 8, Date: "260101120000", Resource: "uid", Ext: "ext=1", Rand: "rand", Counter: "42"},
+		},
+		{
+			name:    "Version 0",
+			stamp:   "0:20:060408:adam@cypherspace.org:rand:1",
+			wantErr: true,
+		},
+		{
+			name:    "Bits not a number",
+			stamp:   "1:x:060408:resource::rand:1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStamp(tt.stamp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStamp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseStamp() = %+v, want %+v", got, tt.want)
+			}
+			if err == nil && got.String() != tt.stamp {
+				t.Errorf("Stamp.String() = %s, want %s", got.String(), tt.stamp)
+			}
+		})
+	}
+}
+
+func TestHashcash_Verify(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	sha1, _ := NewHashcash(HashSHA1, 10*time.Minute)
+	sha256, _ := NewHashcash(HashSHA256, 10*time.Minute)
+
+	stamp := func(h Hashcash, resource string, bits int, date time.Time) string {
+		s := NewStamp(resource, bits, date)
+		return mineStamp(h, s)
+	}
+	valid := stamp(sha1, "uid", 8, now)
+
+	tests := []struct {
+		name     string
+		hashcash Hashcash
+		stamp    string
+		resource string
+		bits     int
+		wantErr  error
+	}{
+		{
+			name:     "Valid SHA-1 stamp",
+			hashcash: sha1,
+			stamp:    valid,
+			resource: "uid",
+			bits:     8,
+		},
+		{
+			name:     "Valid SHA-256 stamp",
+			hashcash: sha256,
+			stamp:    stamp(sha256, "uid", 8, now),
+			resource: "uid",
+			bits:     8,
+		},
+		{
+			name:     "Stamp dated by the day",
+			hashcash: sha1,
+			stamp:    mineStamp(sha1, Stamp{Bits: 8, Date: "260101", Resource: "uid", Rand: "r"}),
+			resource: "uid",
+			bits:     8,
+		},
+		{
+			name:     "More bits than required",
+			hashcash: sha1,
+			stamp:    stamp(sha1, "uid", 10, now),
+			resource: "uid",
+			bits:     8,
+		},
+		{
+			name:     "Too few bits claimed",
+			hashcash: sha1,
+			stamp:    valid,
+			resource: "uid",
+			bits:     12,
+			wantErr:  ErrStampBits,
+		},
+		{
+			name:     "Another resource",
+			hashcash: sha1,
+			stamp:    valid,
+			resource: "other",
+			bits:     8,
+			wantErr:  ErrStampResource,
+		},
+		{
+			name:     "Old stamp",
+			hashcash: sha1,
+			stamp:    stamp(sha1, "uid", 8, now.Add(-time.Hour)),
+			resource: "uid",
+			bits:     8,
+			wantErr:  ErrStampDate,
+		},
+		{
+			name:     "Stamp from the future",
+			hashcash: sha1,
+			stamp:    stamp(sha1, "uid", 8, now.Add(time.Hour)),
+			resource: "uid",
+			bits:     8,
+			wantErr:  ErrStampDate,
+		},
+		{
+			name:     "Hash without the claimed bits",
+			hashcash: sha1,
+			stamp:    "1:40:260101120000:uid::rand:0",
+			resource: "uid",
+			bits:     8,
+			wantErr:  ErrStampHash,
+		},
+		{
+			name:     "SHA-1 stamp checked with SHA-256",
+			hashcash: sha256,
+			stamp:    mineStamp(sha1, Stamp{Bits: 16, Date: "260101120000", Resource: "uid", Rand: "r"}),
+			resource: "uid",
+			bits:     16,
+			wantErr:  ErrStampHash,
+		},
+		{
+			name:     "Malformed date",
+			hashcash: sha1,
+			stamp:    "1:8:2026:uid::rand:0",
+			resource: "uid",
+			bits:     8,
+			wantErr:  ErrStampMalformed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.hashcash.Verify(tt.stamp, tt.resource, tt.bits, now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Hashcash.Verify(%s) error = %v, wantErr %v", tt.stamp, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLeadingZeros(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		hash []byte
+		want int
+	}{
+		{hash: []byte{0x80, 0}, want: 0},
+		{hash: []byte{0x01, 0xff}, want: 7},
+		{hash: []byte{0, 0x10}, want: 11},
+		{hash: []byte{0, 0}, want: 16},
+	}
+	for _, tt := range tests {
+		if got := LeadingZeros(tt.hash); got != tt.want {
+			t.Errorf("LeadingZeros(%x) = %d, want %d", tt.hash, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package pow implements the proof of work algorithms, shared by tcp-server and tcp-client.
+package pow
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const hashLength = 32
+
+// Proof of work algorithms, a challenge without one is keccak.
+const (
+	AlgorithmKeccak   = "keccak"
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmScrypt   = "scrypt"
+	AlgorithmEthash   = "ethash"
+	AlgorithmHashcash = "hashcash"
+)
+
+// Hash functions of Hashcash stamps.
+const (
+	HashSHA1   = "sha1"
+	HashSHA256 = "sha256"
+)
+
+// Params are the cost parameters of memory-hard algorithms: time, memory in KiB
+// and threads for argon2id, N, r and p for scrypt, the epoch and the cache and
+// dataset sizes in bytes for ethash, the hash function for hashcash.
+type Params struct {
+	Time        uint32 `json:"time,omitempty"`
+	Memory      uint32 `json:"memory,omitempty"`
+	Threads     uint8  `json:"threads,omitempty"`
+	N           int    `json:"n,omitempty"`
+	R           int    `json:"r,omitempty"`
+	P           int    `json:"p,omitempty"`
+	Epoch       uint64 `json:"epoch,omitempty"`
+	CacheSize   int    `json:"cache_size,omitempty"`
+	DatasetSize int    `json:"dataset_size,omitempty"`
+	Hash        string `json:"hash,omitempty"`
+}
+
+// Algorithm is the proof of work function. A solution is a nonce whose hash with
+// the challenge has at least difficulty leading zero bits.
+type Algorithm interface {
+	Name() string
+	// Params are the cost parameters sent to the client with the challenge.
+	Params() *Params
+	Hash(challenge string, nonce uint64) []byte
+}
+
+// NewAlgorithm returns the algorithm by name, with its cost parameters taken from params.
+func NewAlgorithm(name string, params Params) (Algorithm, error) {
+	switch name {
+	case AlgorithmKeccak, "":
+		return Keccak{}, nil
+	case AlgorithmArgon2id:
+		if params.Time == 0 || params.Threads == 0 || params.Memory < 8*uint32(params.Threads) {
+			return nil, fmt.Errorf("invalid argon2id parameters: time %d, memory %d KiB, threads %d", params.Time, params.Memory, params.Threads)
+		}
+		return Argon2id{Time: params.Time, Memory: params.Memory, Threads: params.Threads}, nil
+	case AlgorithmScrypt:
+		// the same limits scrypt.Key checks on every call
+		if params.N <= 1 || params.N&(params.N-1) != 0 || params.R <= 0 || params.P <= 0 || uint64(params.R)*uint64(params.P) >= 1<<30 {
+			return nil, fmt.Errorf("invalid scrypt parameters: N %d, r %d, p %d", params.N, params.R, params.P)
+		}
+		return Scrypt{N: params.N, R: params.R, P: params.P}, nil
+	case AlgorithmHashcash:
+		return NewHashcash(params.Hash, 0)
+	default:
+		return nil, fmt.Errorf("unknown pow algorithm '%s'", name)
+	}
+}
+
+// Keccak is a single Keccak-256 of the challenge followed by the nonce.
+type Keccak struct{}
+
+func (Keccak) Name() string {
+	return AlgorithmKeccak
+}
+
+func (Keccak) Params() *Params {
+	return nil
+}
+
+func (Keccak) Hash(challenge string, nonce uint64) []byte {
+	return crypto.Keccak256([]byte(fmt.Sprint(challenge, nonce)))
+}
+
+// Argon2id is memory-hard: every hash takes Memory KiB, so solving can't be sped up
+// much on GPUs or ASICs. The challenge is the salt and the nonce is the password.
+type Argon2id struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+func (a Argon2id) Name() string {
+	return AlgorithmArgon2id
+}
+
+func (a Argon2id) Params() *Params {
+	return &Params{Time: a.Time, Memory: a.Memory, Threads: a.Threads}
+}
+
+func (a Argon2id) Hash(challenge string, nonce uint64) []byte {
+	return argon2.IDKey(strconv.AppendUint(nil, nonce, 10), []byte(challenge), a.Time, a.Memory, a.Threads, hashLength)
+}
+
+// Scrypt is memory-hard with 128*N*R bytes per hash. The challenge is the salt and
+// the nonce is the password.
+type Scrypt struct {
+	N int
+	R int
+	P int
+}
+
+func (s Scrypt) Name() string {
+	return AlgorithmScrypt
+}
+
+func (s Scrypt) Params() *Params {
+	return &Params{N: s.N, R: s.R, P: s.P}
+}
+
+func (s Scrypt) Hash(challenge string, nonce uint64) []byte {
+	// the parameters are checked by NewAlgorithm
+	hash, _ := scrypt.Key(strconv.AppendUint(nil, nonce, 10), []byte(challenge), s.N, s.R, s.P, hashLength)
+	return hash
+}
@@ -0,0 +1,87 @@
+package pow
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		algo    string
+		params  Params
+		want    Algorithm
+		wantErr bool
+	}{
+		{
+			name: "Default is keccak",
+			want: Keccak{},
+		},
+		{
+			name:   "Argon2id",
+			algo:   AlgorithmArgon2id,
+			params: Params{Time: 1, Memory: 16384, Threads: 2, N: 1024},
+			want:   Argon2id{Time: 1, Memory: 16384, Threads: 2},
+		},
+		{
+			name:    "Argon2id without memory",
+			algo:    AlgorithmArgon2id,
+			params:  Params{Time: 1, Memory: 8, Threads: 2},
+			wantErr: true,
+		},
+		{
+			name:   "Scrypt",
+			algo:   AlgorithmScrypt,
+			params: Params{N: 16384, R: 8, P: 1},
+			want:   Scrypt{N: 16384, R: 8, P: 1},
+		},
+		{
+			name:    "Scrypt N not a power of two",
+			algo:    AlgorithmScrypt,
+			params:  Params{N: 1000, R: 8, P: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown algorithm",
+			algo:    "md5",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewAlgorithm(tt.algo, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAlgorithm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NewAlgorithm() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlgorithm_Hash(t *testing.T) {
+	t.Parallel()
+
+	algorithms := []Algorithm{
+		Keccak{},
+		Argon2id{Time: 1, Memory: 64, Threads: 1},
+		Scrypt{N: 16, R: 1, P: 1},
+	}
+	for _, a := range algorithms {
+		t.Run(a.Name(), func(t *testing.T) {
+			hash := a.Hash("challenge", 42)
+			if len(hash) != hashLength {
+				t.Fatalf("Hash() length = %d, want %d", len(hash), hashLength)
+			}
+			if !bytes.Equal(hash, a.Hash("challenge", 42)) {
+				t.Error("Hash() is not deterministic")
+			}
+			if bytes.Equal(hash, a.Hash("challenge", 43)) || bytes.Equal(hash, a.Hash("another", 42)) {
+				t.Error("Hash() ignores its input")
+			}
+		})
+	}
+}
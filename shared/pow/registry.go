@@ -0,0 +1,69 @@
+package pow
+
+import "errors"
+
+var ErrNoCommonAlgorithm = errors.New("no supported pow algorithm")
+
+// Issued is what a challenge was issued with, its solution is verified against it.
+type Issued struct {
+	Difficulty int    `json:"difficulty,omitempty"`
+	Algorithm  string `json:"algorithm,omitempty"`
+	Epoch      uint64 `json:"epoch,omitempty"`
+}
+
+// Registry holds the algorithms the server issues challenges with, in preference order.
+// Each has a difficulty offset: the bits taken off the difficulty, so that algorithms
+// with costly hashes take about as long to solve as keccak.
+type Registry struct {
+	order   []string
+	entries map[string]entry
+}
+
+type entry struct {
+	algorithm Algorithm
+	offset    int
+}
+
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]entry)}
+}
+
+// Register adds the algorithm after the ones registered before, replacing one with the same name.
+func (r *Registry) Register(algorithm Algorithm, offset int) {
+	name := algorithm.Name()
+	if _, ok := r.entries[name]; !ok {
+		r.order = append(r.order, name)
+	}
+	r.entries[name] = entry{algorithm: algorithm, offset: offset}
+}
+
+// Get returns the algorithm by name, challenges without one are keccak.
+func (r *Registry) Get(name string) (Algorithm, int, bool) {
+	if name == "" {
+		name = AlgorithmKeccak
+	}
+	e, ok := r.entries[name]
+	return e.algorithm, e.offset, ok
+}
+
+// Names are the registered algorithms in preference order.
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.order...)
+}
+
+// Negotiate picks the most preferred algorithm the client supports. Clients that
+// don't list their algorithms predate negotiation and only solve keccak.
+func (r *Registry) Negotiate(supported []string) (Algorithm, int, error) {
+	if len(supported) == 0 {
+		supported = []string{AlgorithmKeccak}
+	}
+	for _, name := range r.order {
+		for _, s := range supported {
+			if s == name {
+				e := r.entries[name]
+				return e.algorithm, e.offset, nil
+			}
+		}
+	}
+	return nil, 0, ErrNoCommonAlgorithm
+}
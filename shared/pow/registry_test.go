@@ -0,0 +1,79 @@
+package pow
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegistry_Negotiate(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	registry.Register(Argon2id{Time: 1, Memory: 1024, Threads: 1}, 16)
+	registry.Register(Keccak{}, 0)
+
+	tests := []struct {
+		name       string
+		supported  []string
+		want       string
+		wantOffset int
+		wantErr    error
+	}{
+		{
+			name:       "Preferred algorithm wins",
+			supported:  []string{AlgorithmKeccak, AlgorithmArgon2id},
+			want:       AlgorithmArgon2id,
+			wantOffset: 16,
+		},
+		{
+			name:      "Only common algorithm",
+			supported: []string{AlgorithmScrypt, AlgorithmKeccak},
+			want:      AlgorithmKeccak,
+		},
+		{
+			name: "Client without algorithms solves keccak",
+			want: AlgorithmKeccak,
+		},
+		{
+			name:      "No common algorithm",
+			supported: []string{AlgorithmScrypt},
+			wantErr:   ErrNoCommonAlgorithm,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, offset, err := registry.Negotiate(tt.supported)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Registry.Negotiate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Name() != tt.want || offset != tt.wantOffset {
+				t.Errorf("Registry.Negotiate() = %s, %d, want %s, %d", got.Name(), offset, tt.want, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestRegistry_Get(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	registry.Register(Keccak{}, 0)
+	registry.Register(Scrypt{N: 1024, R: 8, P: 1}, 10)
+	registry.Register(Scrypt{N: 2048, R: 8, P: 1}, 11)
+
+	if got := registry.Names(); len(got) != 2 || got[0] != AlgorithmKeccak || got[1] != AlgorithmScrypt {
+		t.Errorf("Registry.Names() = %v, want [keccak scrypt]", got)
+	}
+	if got, _, ok := registry.Get(""); !ok || got.Name() != AlgorithmKeccak {
+		t.Error("Registry.Get() of a challenge without algorithm isn't keccak")
+	}
+	if got, offset, ok := registry.Get(AlgorithmScrypt); !ok || got != (Scrypt{N: 2048, R: 8, P: 1}) || offset != 11 {
+		t.Errorf("Registry.Get(scrypt) = %v, %d, want the replacement", got, offset)
+	}
+	if _, _, ok := registry.Get(AlgorithmEthash); ok {
+		t.Error("Registry.Get() found an unregistered algorithm")
+	}
+}
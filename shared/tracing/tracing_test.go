@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInjectExtract(t *testing.T) {
+	t.Parallel()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	remote := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantTrace map[string]string
+	}{
+		{
+			name:      "Sampled span",
+			ctx:       remote,
+			wantTrace: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		},
+		{
+			name:      "No span",
+			ctx:       context.Background(),
+			wantTrace: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			carrier := Inject(tt.ctx)
+			if !reflect.DeepEqual(carrier, tt.wantTrace) {
+				t.Fatalf("Inject() = %v, want %v", carrier, tt.wantTrace)
+			}
+
+			got := trace.SpanContextFromContext(Extract(context.Background(), carrier))
+			want := trace.SpanContextFromContext(tt.ctx)
+			if got.TraceID() != want.TraceID() || got.SpanID() != want.SpanID() {
+				t.Errorf("Extract() span context = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Init(context.Background(), "tcp-server", "zipkin", ""); err == nil {
+		t.Error("Init() accepted an unknown exporter")
+	}
+	shutdown, err := Init(context.Background(), "tcp-server", ExporterNone, "")
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/pullya/wow_tcp_server/shared/tracing"
 	"github.com/pullya/wow_tcp_server/tcp-client/internal/app"
 	"github.com/pullya/wow_tcp_server/tcp-client/internal/client"
 	"github.com/pullya/wow_tcp_server/tcp-client/internal/config"
@@ -14,6 +16,9 @@ import (
 
 func init() {
 	config.ReadConfig()
+	if err := config.ParseFlags(os.Args[1:]); err != nil {
+		log.Fatalf("failed to parse flags: %v", err)
+	}
 	config.InitLogger()
 	log.SetLevel(config.Config.LogLevel.ToLogrusFormat())
 }
@@ -31,10 +36,28 @@ func main() {
 		cancel()
 	}()
 
+	if config.Config.ReadinessURL != "" {
+		timeout := time.Millisecond * time.Duration(config.Config.ReadinessTimeout)
+		if err := client.WaitReady(ctx, config.Config.ReadinessURL, timeout, 500*time.Millisecond); err != nil {
+			config.Logger.Fatalf("Error while waiting for tcp-server: %v", err)
+		}
+	}
+
+	shutdownTracing, err := tracing.Init(ctx, config.Config.ServiceName, config.Config.TracingExporter, config.Config.TracingEndpoint)
+	if err != nil {
+		config.Logger.Fatalf("Error while initializing tracing: %v", err)
+	}
+
 	client := client.New(config.BuildAddress(config.Config.Port))
-	challenge := app.NewChallenge()
+	challenge := app.NewChallenge(config.Config.PowAlgorithms...)
 
 	app := app.New(&client, &challenge)
 
 	app.Run(ctx)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		config.Logger.Errorf("Error while flushing traces: %v", err)
+	}
 }
@@ -2,14 +2,23 @@ package app
 
 import (
 	"context"
+	"errors"
+	"net"
 	"sync"
 	"time"
 
+	"github.com/pullya/wow_tcp_server/shared/tracing"
 	"github.com/pullya/wow_tcp_server/tcp-client/internal/client"
 	"github.com/pullya/wow_tcp_server/tcp-client/internal/config"
 	"github.com/pullya/wow_tcp_server/tcp-client/internal/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/pullya/wow_tcp_server/tcp-client/internal/app")
+
 type App struct {
 	client    client.ClientProvider
 	challenge Challenger
@@ -43,42 +52,69 @@ func (a *App) Run(ctx context.Context) {
 func (a *App) startWork(ctx context.Context, id int) {
 	defer a.wg.Done()
 
-	conn, err := a.client.Run(ctx)
+	ctx, span := tracer.Start(ctx, "wow.session",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.Int("wow.connection", id),
+			attribute.String("wow.mode", config.Config.Mode),
+		))
+	defer span.End()
+
+	if config.Config.Mode == config.ModeSession {
+		a.runSession(ctx, id)
+		return
+	}
+	a.runLegacy(ctx, id)
+}
+
+// runSession requests a challenge, submits the solution and reads the quote over one connection.
+func (a *App) runSession(ctx context.Context, id int) {
+	conn, err := a.connect(ctx)
 	if err != nil {
 		config.Logger.WithField("connection", id).Errorf("Error while establishing connection to tcp-server: %v", err)
 		return
 	}
 	defer a.client.CloseConn(conn)
 
-	requestMessage := model.PrepareMessage("", model.MessageTypeRequest, "", 0)
-
-	if err = a.client.SendMessage(ctx, conn, requestMessage.AsJsonString()); err != nil {
-		config.Logger.WithField("connection", id).Errorf("Error while sending request message: %v", err)
+	sm, err := a.requestChallenge(ctx, conn, id)
+	if err != nil {
 		return
 	}
 
-	taskMessage, err := a.client.ReceiveMessage(ctx, conn)
+	solution, err := a.solve(ctx, sm, id)
 	if err != nil {
-		config.Logger.WithField("connection", id).Errorf("Error reading PoW challenge: %v", err)
 		return
 	}
 
-	config.Logger.WithField("connection", id).Debugf("Message from server received: %s", taskMessage)
+	if err = a.client.SendMessage(ctx, conn, solution.AsJsonString()); err != nil {
+		config.Logger.WithField("connection", id).Errorf("Error while sending message: %v", err)
+		return
+	}
 
-	sm, err := model.ParseServerMessage(taskMessage)
+	a.receiveWOW(ctx, conn, id)
+}
+
+// runLegacy opens separate connections for the challenge and for the solution.
+func (a *App) runLegacy(ctx context.Context, id int) {
+	conn, err := a.connect(ctx)
 	if err != nil {
-		config.Logger.WithField("connection", id).Errorf("Unable to unmarshal server message: %v\n", err)
+		config.Logger.WithField("connection", id).Errorf("Error while establishing connection to tcp-server: %v", err)
 		return
 	}
-	a.client.CloseConn(conn)
+	defer a.client.CloseConn(conn)
 
-	a.challenge.SetDifficulty(sm.Difficulty)
-	nonce := a.challenge.GenerateSolution(ctx, sm.MessageString)
-	config.Logger.WithField("connection", id).Infof("Found solution: %s", nonce)
+	sm, err := a.requestChallenge(ctx, conn, id)
+	if err != nil {
+		return
+	}
+	a.client.CloseConn(conn)
 
-	responseMessage := model.PrepareMessage(sm.RequestID, model.MessageTypeSolution, nonce, sm.Difficulty)
+	responseMessage, err := a.solve(ctx, sm, id)
+	if err != nil {
+		return
+	}
 
-	conn, err = a.client.Run(ctx)
+	conn, err = a.connect(ctx)
 	if err != nil {
 		config.Logger.WithField("connection", id).Errorf("Error while establishing connection to tcp-server: %v", err)
 		return
@@ -89,15 +125,124 @@ func (a *App) startWork(ctx context.Context, id int) {
 		return
 	}
 
+	a.receiveWOW(ctx, conn, id)
+}
+
+func (a *App) connect(ctx context.Context) (net.Conn, error) {
+	ctx, span := tracer.Start(ctx, "connect")
+	conn, err := a.client.Run(ctx)
+	endSpan(span, err)
+	return conn, err
+}
+
+func (a *App) requestChallenge(ctx context.Context, conn net.Conn, id int) (sm model.Message, err error) {
+	requestMessage := model.PrepareMessage("", model.MessageTypeRequest, "", 0)
+	requestMessage.ClientID = config.Config.ClientID
+	requestMessage.Algorithms = a.challenge.Algorithms()
+	if config.Config.Daily {
+		requestMessage.MessageType = model.MessageTypeDaily
+	} else if filter := quoteFilter(); !filter.IsEmpty() {
+		requestMessage.Filter = &filter
+	}
+	requestMessage.Trace = tracing.Inject(ctx)
+
+	ctx, span := tracer.Start(ctx, "request challenge")
+	defer func() { endSpan(span, err) }()
+
+	if err := a.client.SendMessage(ctx, conn, requestMessage.AsJsonString()); err != nil {
+		config.Logger.WithField("connection", id).Errorf("Error while sending request message: %v", err)
+		return model.Message{}, err
+	}
+
+	taskMessage, err := a.client.ReceiveMessage(ctx, conn)
+	if err != nil {
+		config.Logger.WithField("connection", id).Errorf("Error reading PoW challenge: %v", err)
+		return model.Message{}, err
+	}
+
+	config.Logger.WithField("connection", id).Debugf("Message from server received: %s", taskMessage)
+
+	sm, err = model.ParseServerMessage(taskMessage)
+	if err != nil {
+		config.Logger.WithField("connection", id).Errorf("Unable to unmarshal server message: %v\n", err)
+		return model.Message{}, err
+	}
+	if sm.MessageType == model.MessageTypeError {
+		err = errors.New(sm.MessageString)
+		config.Logger.WithField("connection", id).Errorf("Server refused to send a challenge: %s", sm.MessageString)
+		return model.Message{}, err
+	}
+	span.SetAttributes(attribute.Int("wow.difficulty", sm.Difficulty))
+
+	return sm, nil
+}
+
+func (a *App) solve(ctx context.Context, sm model.Message, id int) (model.Message, error) {
+	solveCtx, span := tracer.Start(ctx, "solve", trace.WithAttributes(
+		attribute.Int("wow.difficulty", sm.Difficulty),
+		attribute.String("wow.algorithm", sm.Algorithm),
+	))
+	algorithm, err := a.challenge.Algorithm(sm.Algorithm, sm.Params)
+	if err != nil {
+		endSpan(span, err)
+		config.Logger.WithField("connection", id).Errorf("Unable to solve the challenge: %v", err)
+		return model.Message{}, err
+	}
+	nonce, err := a.challenge.GenerateSolution(solveCtx, sm.MessageString, algorithm, sm.Difficulty)
+	endSpan(span, err)
+	if err != nil {
+		config.Logger.WithField("connection", id).Errorf("Gave up solving the challenge: %v", err)
+		return model.Message{}, err
+	}
+	config.Logger.WithField("connection", id).Infof("Found solution: %s", nonce)
+
+	solution := model.PrepareMessage(sm.RequestID, model.MessageTypeSolution, nonce, sm.Difficulty)
+	solution.ClientID = config.Config.ClientID
+	solution.Trace = tracing.Inject(ctx)
+
+	return solution, nil
+}
+
+func (a *App) receiveWOW(ctx context.Context, conn net.Conn, id int) {
+	var err error
+	ctx, span := tracer.Start(ctx, "receive quote")
+	defer func() { endSpan(span, err) }()
+
 	message, err := a.client.ReceiveMessage(ctx, conn)
 	if err != nil {
 		return
 	}
 	config.Logger.WithField("connection", id).Infof("Message from server received: %s", message)
-	sm, err = model.ParseServerMessage(message)
+	sm, err := model.ParseServerMessage(message)
 	if err != nil {
 		config.Logger.WithField("connection", id).Errorf("Unable to unmarshal server message: %v\n", err)
 		return
 	}
-	config.Logger.WithField("connection", id).Infof("Words of Wisdom: %s", sm.MessageString)
+	if sm.MessageType == model.MessageTypeError {
+		err = errors.New(sm.MessageString)
+		config.Logger.WithField("connection", id).Errorf("Server refused to send a quote: %s", sm.MessageString)
+		return
+	}
+	// servers without structured quotes only fill MessageString
+	if sm.Quote == nil {
+		config.Logger.WithField("connection", id).Infof("Words of Wisdom: %s", sm.MessageString)
+		return
+	}
+	config.Logger.WithField("connection", id).WithField("quote_id", sm.Quote.ID).Infof("Words of Wisdom: %s", sm.Quote)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func quoteFilter() model.QuoteFilter {
+	return model.QuoteFilter{
+		Tags:     config.Config.Tags,
+		Author:   config.Config.Author,
+		Language: config.Config.Language,
+	}
 }
@@ -10,10 +10,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pullya/wow_tcp_server/shared/pow"
 	"github.com/pullya/wow_tcp_server/tcp-client/internal/app/mocks"
 	"github.com/pullya/wow_tcp_server/tcp-client/internal/client"
 	clientMocks "github.com/pullya/wow_tcp_server/tcp-client/internal/client/mocks"
 	"github.com/pullya/wow_tcp_server/tcp-client/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-client/internal/model"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -67,6 +69,7 @@ func TestWowService_startWork(t *testing.T) {
 			fields: func() fields {
 				clientMock := &clientMocks.ClientProvider{}
 				challengeMock := &mocks.Challenger{}
+				challengeMock.On("Algorithms").Return([]string(nil))
 				wg := sync.WaitGroup{}
 				wg.Add(1)
 
@@ -92,6 +95,7 @@ func TestWowService_startWork(t *testing.T) {
 			fields: func() fields {
 				clientMock := &clientMocks.ClientProvider{}
 				challengeMock := &mocks.Challenger{}
+				challengeMock.On("Algorithms").Return([]string(nil))
 				wg := sync.WaitGroup{}
 				wg.Add(1)
 
@@ -118,6 +122,7 @@ func TestWowService_startWork(t *testing.T) {
 			fields: func() fields {
 				clientMock := &clientMocks.ClientProvider{}
 				challengeMock := &mocks.Challenger{}
+				challengeMock.On("Algorithms").Return([]string(nil))
 				wg := sync.WaitGroup{}
 				wg.Add(1)
 
@@ -144,6 +149,7 @@ func TestWowService_startWork(t *testing.T) {
 			fields: func() fields {
 				clientMock := &clientMocks.ClientProvider{}
 				challengeMock := &mocks.Challenger{}
+				challengeMock.On("Algorithms").Return([]string(nil))
 				wg := sync.WaitGroup{}
 				wg.Add(1)
 
@@ -152,8 +158,8 @@ func TestWowService_startWork(t *testing.T) {
 				clientMock.On("SendMessage", mock.Anything, tConn, []byte("{\"request_id\":\"\",\"message_type\":\"request\",\"message_string\":\"\",\"difficulty\":0}\n")).Return(nil)
 				clientMock.On("ReceiveMessage", mock.Anything, tConn).Return("{\"request_id\":\"1q2w3e\",\"message_type\":\"challenge\",\"message_string\":\"Find a string that, when hashed, can be proofed 1\",\"difficulty\":10}", nil)
 
-				challengeMock.On("SetDifficulty", 10).Return()
-				challengeMock.On("GenerateSolution", mock.Anything, "Find a string that, when hashed, can be proofed 1").Return("123")
+				challengeMock.On("Algorithm", "", (*model.PowParams)(nil)).Return(pow.Keccak{}, nil)
+				challengeMock.On("GenerateSolution", mock.Anything, "Find a string that, when hashed, can be proofed 1", pow.Keccak{}, 10).Return("123", nil)
 
 				clientMock.On("SendMessage", mock.Anything, tConn, mock.Anything).Return(errors.New("error"))
 
@@ -193,3 +199,172 @@ func TestWowService_startWork(t *testing.T) {
 		})
 	}
 }
+
+func TestApp_runSession(t *testing.T) {
+	tConn := *new(net.Conn)
+	config.Config.ServiceName = "tcp-client"
+	config.InitLogger()
+
+	clientMock := &clientMocks.ClientProvider{}
+	challengeMock := &mocks.Challenger{}
+	challengeMock.On("Algorithms").Return([]string(nil))
+
+	clientMock.On("Run", mock.Anything).Return(tConn, nil)
+	clientMock.On("CloseConn", tConn).Return()
+	clientMock.On("SendMessage", mock.Anything, tConn, []byte("{\"request_id\":\"\",\"message_type\":\"request\",\"message_string\":\"\",\"difficulty\":0}\n")).Return(nil).Once()
+	clientMock.On("ReceiveMessage", mock.Anything, tConn).Return("{\"request_id\":\"1q2w3e\",\"message_type\":\"challenge\",\"message_string\":\"Find a string that, when hashed, can be proofed 1\",\"difficulty\":10}", nil).Once()
+	clientMock.On("SendMessage", mock.Anything, tConn, []byte("{\"request_id\":\"1q2w3e\",\"message_type\":\"solution\",\"message_string\":\"123\",\"difficulty\":10}\n")).Return(nil).Once()
+	clientMock.On("ReceiveMessage", mock.Anything, tConn).Return("{\"request_id\":\"1q2w3e\",\"message_type\":\"wow\",\"message_string\":\"Random Word of Wisdom\",\"difficulty\":0}", nil).Once()
+
+	challengeMock.On("Algorithm", "", (*model.PowParams)(nil)).Return(pow.Keccak{}, nil)
+	challengeMock.On("GenerateSolution", mock.Anything, "Find a string that, when hashed, can be proofed 1", pow.Keccak{}, 10).Return("123", nil)
+
+	var logBuffer bytes.Buffer
+
+	log.StandardLogger().SetLevel(log.DebugLevel)
+	log.StandardLogger().SetOutput(&logBuffer)
+
+	a := &App{
+		client:    clientMock,
+		challenge: challengeMock,
+		wg:        &sync.WaitGroup{},
+	}
+	a.runSession(context.Background(), 12)
+
+	clientMock.AssertExpectations(t)
+	clientMock.AssertNumberOfCalls(t, "Run", 1)
+	assert.Contains(t, logBuffer.String(), "msg=\"Words of Wisdom: Random Word of Wisdom\" connection=12 service=tcp-client\n")
+}
+
+func TestApp_receiveWOW_quote(t *testing.T) {
+	tConn := *new(net.Conn)
+	config.Config.ServiceName = "tcp-client"
+	config.InitLogger()
+
+	clientMock := &clientMocks.ClientProvider{}
+	clientMock.On("ReceiveMessage", mock.Anything, tConn).Return("{\"request_id\":\"1q2w3e\",\"message_type\":\"wow\",\"message_string\":\"Be yourself\",\"difficulty\":0,"+
+		"\"quote\":{\"id\":\"q1\",\"text\":\"Be yourself\",\"author\":\"Oscar Wilde\",\"language\":\"en\",\"tags\":[\"life\"]}}", nil).Once()
+
+	var logBuffer bytes.Buffer
+
+	log.StandardLogger().SetLevel(log.DebugLevel)
+	log.StandardLogger().SetOutput(&logBuffer)
+
+	a := &App{
+		client: clientMock,
+		wg:     &sync.WaitGroup{},
+	}
+	a.receiveWOW(context.Background(), tConn, 12)
+
+	clientMock.AssertExpectations(t)
+	assert.Contains(t, logBuffer.String(), "msg=\"Words of Wisdom: Be yourself - Oscar Wilde [en] #life\" connection=12 quote_id=q1 service=tcp-client\n")
+}
+
+func TestApp_requestChallenge_filter(t *testing.T) {
+	tConn := *new(net.Conn)
+	config.Config.ServiceName = "tcp-client"
+	config.Config.Tags = []string{"life"}
+	config.Config.Author = "Seneca"
+	defer func() {
+		config.Config.Tags = nil
+		config.Config.Author = ""
+	}()
+	config.InitLogger()
+
+	clientMock := &clientMocks.ClientProvider{}
+	clientMock.On("SendMessage", mock.Anything, tConn, []byte("{\"request_id\":\"\",\"message_type\":\"request\",\"message_string\":\"\",\"difficulty\":0,\"filter\":{\"tags\":[\"life\"],\"author\":\"Seneca\"}}\n")).Return(nil).Once()
+	clientMock.On("ReceiveMessage", mock.Anything, tConn).Return("{\"request_id\":\"1q2w3e\",\"message_type\":\"challenge\",\"message_string\":\"Find a string that, when hashed, can be proofed 1\",\"difficulty\":10}", nil).Once()
+
+	challengeMock := &mocks.Challenger{}
+	challengeMock.On("Algorithms").Return([]string(nil))
+
+	a := &App{
+		client:    clientMock,
+		challenge: challengeMock,
+		wg:        &sync.WaitGroup{},
+	}
+	if _, err := a.requestChallenge(context.Background(), tConn, 12); err != nil {
+		t.Fatalf("App.requestChallenge() error = %v", err)
+	}
+	clientMock.AssertExpectations(t)
+}
+
+func TestApp_requestChallenge_daily(t *testing.T) {
+	tConn := *new(net.Conn)
+	config.Config.ServiceName = "tcp-client"
+	config.Config.Daily = true
+	config.Config.Author = "Seneca"
+	defer func() {
+		config.Config.Daily = false
+		config.Config.Author = ""
+	}()
+	config.InitLogger()
+
+	clientMock := &clientMocks.ClientProvider{}
+	clientMock.On("SendMessage", mock.Anything, tConn, []byte("{\"request_id\":\"\",\"message_type\":\"daily\",\"message_string\":\"\",\"difficulty\":0}\n")).Return(nil).Once()
+	clientMock.On("ReceiveMessage", mock.Anything, tConn).Return("{\"request_id\":\"1q2w3e\",\"message_type\":\"challenge\",\"message_string\":\"Find a string that, when hashed, can be proofed 1\",\"difficulty\":10}", nil).Once()
+
+	challengeMock := &mocks.Challenger{}
+	challengeMock.On("Algorithms").Return([]string(nil))
+
+	a := &App{
+		client:    clientMock,
+		challenge: challengeMock,
+		wg:        &sync.WaitGroup{},
+	}
+	if _, err := a.requestChallenge(context.Background(), tConn, 12); err != nil {
+		t.Fatalf("App.requestChallenge() error = %v", err)
+	}
+	clientMock.AssertExpectations(t)
+}
+
+func TestApp_requestChallenge_algorithms(t *testing.T) {
+	tConn := *new(net.Conn)
+	config.Config.ServiceName = "tcp-client"
+	config.InitLogger()
+
+	clientMock := &clientMocks.ClientProvider{}
+	clientMock.On("SendMessage", mock.Anything, tConn, []byte("{\"request_id\":\"\",\"message_type\":\"request\",\"message_string\":\"\",\"difficulty\":0,\"algorithms\":[\"scrypt\",\"ethash\"]}\n")).Return(nil).Once()
+	clientMock.On("ReceiveMessage", mock.Anything, tConn).Return("{\"request_id\":\"\",\"message_type\":\"error\",\"message_string\":\"no supported pow algorithm\",\"difficulty\":0}", nil).Once()
+
+	challengeMock := &mocks.Challenger{}
+	challengeMock.On("Algorithms").Return([]string{model.AlgorithmScrypt, model.AlgorithmEthash})
+
+	var logBuffer bytes.Buffer
+
+	log.StandardLogger().SetLevel(log.DebugLevel)
+	log.StandardLogger().SetOutput(&logBuffer)
+
+	a := &App{
+		client:    clientMock,
+		challenge: challengeMock,
+		wg:        &sync.WaitGroup{},
+	}
+	if _, err := a.requestChallenge(context.Background(), tConn, 12); err == nil {
+		t.Fatal("App.requestChallenge() accepted a refusal")
+	}
+	clientMock.AssertExpectations(t)
+	assert.Contains(t, logBuffer.String(), "msg=\"Server refused to send a challenge: no supported pow algorithm\" connection=12 service=tcp-client\n")
+}
+
+func TestApp_receiveWOW_error(t *testing.T) {
+	tConn := *new(net.Conn)
+	config.Config.ServiceName = "tcp-client"
+	config.InitLogger()
+
+	clientMock := &clientMocks.ClientProvider{}
+	clientMock.On("ReceiveMessage", mock.Anything, tConn).Return("{\"request_id\":\"1q2w3e\",\"message_type\":\"error\",\"message_string\":\"no matching quote\",\"difficulty\":0}", nil).Once()
+
+	var logBuffer bytes.Buffer
+
+	log.StandardLogger().SetLevel(log.DebugLevel)
+	log.StandardLogger().SetOutput(&logBuffer)
+
+	a := &App{
+		client: clientMock,
+		wg:     &sync.WaitGroup{},
+	}
+	a.receiveWOW(context.Background(), tConn, 12)
+
+	assert.Contains(t, logBuffer.String(), "msg=\"Server refused to send a quote: no matching quote\" connection=12 service=tcp-client\n")
+}
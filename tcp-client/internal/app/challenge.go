@@ -3,45 +3,174 @@ package app
 import (
 	"context"
 	"fmt"
-	"math/big"
+	"slices"
+	"sync"
+	"time"
 
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pullya/wow_tcp_server/shared/pow"
+	"github.com/pullya/wow_tcp_server/tcp-client/internal/model"
 )
 
 //go:generate mockery --name=Challenger --output=mocks --case=underscore
 type Challenger interface {
-	GenerateSolution(ctx context.Context, challenge string) string
-	SetDifficulty(diff int)
+	// GenerateSolution mines the challenge until it finds a solution or ctx is done.
+	GenerateSolution(ctx context.Context, challenge string, algorithm pow.Algorithm, difficulty int) (string, error)
+	// Algorithm builds the algorithm of the challenge, refusing ones the client doesn't solve.
+	Algorithm(name string, params *model.PowParams) (pow.Algorithm, error)
+	// Algorithms are the ones the client solves, listed in its requests.
+	Algorithms() []string
 }
 
+// solver builds the algorithm of a challenge from the parameters the server sent.
+type solver func(c *Challenge, params model.PowParams) (pow.Algorithm, error)
+
+// solvers are the known algorithms in the order the client lists them.
+var (
+	solverNames = []string{model.AlgorithmKeccak, model.AlgorithmArgon2id, model.AlgorithmScrypt, model.AlgorithmEthash, model.AlgorithmHashcash}
+	solvers     = map[string]solver{
+		model.AlgorithmKeccak:   newAlgorithm(model.AlgorithmKeccak),
+		model.AlgorithmArgon2id: newAlgorithm(model.AlgorithmArgon2id),
+		model.AlgorithmScrypt:   newAlgorithm(model.AlgorithmScrypt),
+		model.AlgorithmEthash:   (*Challenge).ethashAt,
+		model.AlgorithmHashcash: newAlgorithm(model.AlgorithmHashcash),
+	}
+)
+
+// Limits on the parameters a server may ask for, so it can't make the client allocate
+// unbounded memory or spend unbounded time on a single hash.
+const (
+	maxArgon2Time        = 16
+	maxArgon2Memory      = 256 << 10 // KiB
+	maxArgon2Threads     = 16
+	maxScryptMemory      = 256 << 20 // bytes, 128 * N * r
+	maxScryptP           = 16
+	maxEthashCacheSize   = 16 << 20 // bytes
+	maxEthashDatasetSize = 1 << 30  // bytes
+)
+
+func newAlgorithm(name string) solver {
+	return func(_ *Challenge, params model.PowParams) (pow.Algorithm, error) {
+		return pow.NewAlgorithm(name, params)
+	}
+}
+
+// Challenge is shared by all connections, so everything a solution depends on is passed
+// per call. Only the ethash instance is cached, under mu, its datasets are built outside it.
 type Challenge struct {
-	difficulty int
+	algorithms []string
+
+	mu     *sync.Mutex
+	ethash *pow.Ethash
+}
+
+// NewChallenge solves challenges with any of the algorithms, or with any known one if none are given.
+func NewChallenge(algorithms ...string) Challenge {
+	if len(algorithms) == 0 {
+		algorithms = solverNames
+	}
+	return Challenge{algorithms: algorithms, mu: &sync.Mutex{}}
+}
+
+func (c *Challenge) Algorithms() []string {
+	return append([]string(nil), c.algorithms...)
+}
+
+func (c *Challenge) Algorithm(name string, params *model.PowParams) (pow.Algorithm, error) {
+	if name == "" {
+		name = model.AlgorithmKeccak
+	}
+	solve, ok := solvers[name]
+	if !ok || !slices.Contains(c.algorithms, name) {
+		return nil, fmt.Errorf("pow algorithm '%s' is not allowed", name)
+	}
+	if params == nil {
+		params = &model.PowParams{}
+	}
+	if err := checkLimits(name, *params); err != nil {
+		return nil, err
+	}
+
+	return solve(c, *params)
+}
+
+// checkLimits refuses parameters above the client maximums. Whether they are valid
+// at all is checked when the algorithm is built.
+func checkLimits(name string, params model.PowParams) error {
+	switch name {
+	case model.AlgorithmArgon2id:
+		if params.Time > maxArgon2Time || params.Memory > maxArgon2Memory || params.Threads > maxArgon2Threads {
+			return fmt.Errorf("argon2id parameters above the client limits: time %d, memory %d KiB, threads %d", params.Time, params.Memory, params.Threads)
+		}
+	case model.AlgorithmScrypt:
+		// N and r are bounded first so the product can't overflow
+		if params.N > maxScryptMemory/128 || params.R > maxScryptMemory/128 ||
+			128*int64(params.N)*int64(params.R) > maxScryptMemory || params.P > maxScryptP {
+			return fmt.Errorf("scrypt parameters above the client limits: N %d, r %d, p %d", params.N, params.R, params.P)
+		}
+	case model.AlgorithmEthash:
+		if params.CacheSize > maxEthashCacheSize || params.DatasetSize > maxEthashDatasetSize {
+			return fmt.Errorf("ethash sizes above the client limits: cache %d, dataset %d bytes", params.CacheSize, params.DatasetSize)
+		}
+	}
+	return nil
 }
 
-func NewChallenge() Challenge {
-	return Challenge{}
+// ethashAt mines with the full dataset of the announced epoch. The dataset is built
+// once per epoch and reused by all connections, mu is only held to pick the instance.
+func (c *Challenge) ethashAt(params model.PowParams) (pow.Algorithm, error) {
+	ethash, err := c.ethashFor(params.CacheSize, params.DatasetSize)
+	if err != nil {
+		return nil, err
+	}
+	return ethash.At(params.Epoch), nil
 }
 
-func (c *Challenge) SetDifficulty(diff int) {
-	c.difficulty = diff
+// ethashFor returns the cached instance, replacing it if the server changed the sizes.
+func (c *Challenge) ethashFor(cacheSize, datasetSize int) (*pow.Ethash, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ethash == nil || c.ethash.Params().CacheSize != cacheSize || c.ethash.Params().DatasetSize != datasetSize {
+		ethash, err := pow.NewEthash(cacheSize, datasetSize, 0, true)
+		if err != nil {
+			return nil, err
+		}
+		c.ethash = ethash
+	}
+	return c.ethash, nil
 }
 
-func (c *Challenge) GenerateSolution(ctx context.Context, challenge string) string {
-	nonce := c.mineEthash(ctx, challenge)
-	return fmt.Sprint(nonce)
+// GenerateSolution returns the nonce or, for hashcash, the stamp with the challenge as its resource.
+func (c *Challenge) GenerateSolution(ctx context.Context, challenge string, algorithm pow.Algorithm, difficulty int) (string, error) {
+	if _, ok := algorithm.(pow.Stamper); ok {
+		stamp := pow.NewStamp(challenge, difficulty, time.Now())
+		counter, err := mine(ctx, stamp.Prefix(), algorithm, difficulty)
+		if err != nil {
+			return "", err
+		}
+		stamp.Counter = fmt.Sprint(counter)
+		return stamp.String(), nil
+	}
+	nonce, err := mine(ctx, challenge, algorithm, difficulty)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(nonce), nil
 }
 
-func (c *Challenge) mineEthash(ctx context.Context, challenge string) uint64 {
+// mine tries nonces from zero, checking ctx before each hash since memory-hard ones are slow.
+func mine(ctx context.Context, challenge string, algorithm pow.Algorithm, difficulty int) (uint64, error) {
 	nonce := uint64(0)
-	target := new(big.Int)
-	target.Exp(big.NewInt(2), big.NewInt(int64(256-c.difficulty)), nil)
 
 	for {
-		hash := crypto.Keccak256([]byte(fmt.Sprint(challenge, nonce)))
-		hashInt := new(big.Int).SetBytes(hash)
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		hash := algorithm.Hash(challenge, nonce)
 
-		if hashInt.Cmp(target) == -1 {
-			return nonce
+		// counted in bits, since a SHA-1 hash is shorter than the others
+		if pow.LeadingZeros(hash) >= difficulty {
+			return nonce, nil
 		}
 
 		nonce++
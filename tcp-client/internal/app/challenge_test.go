@@ -0,0 +1,176 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/shared/pow"
+	"github.com/pullya/wow_tcp_server/tcp-client/internal/model"
+)
+
+func TestChallenge_Algorithm(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		algorithms []string
+		algo       string
+		params     *model.PowParams
+		wantErr    bool
+	}{
+		{
+			name: "Old server without algorithm",
+		},
+		{
+			name:   "Any algorithm allowed",
+			algo:   model.AlgorithmScrypt,
+			params: &model.PowParams{N: 1024, R: 8, P: 1},
+		},
+		{
+			name:       "Allowed algorithm",
+			algorithms: []string{model.AlgorithmArgon2id},
+			algo:       model.AlgorithmArgon2id,
+			params:     &model.PowParams{Time: 1, Memory: 1024, Threads: 1},
+		},
+		{
+			name:       "Algorithm not allowed",
+			algorithms: []string{model.AlgorithmKeccak},
+			algo:       model.AlgorithmArgon2id,
+			params:     &model.PowParams{Time: 1, Memory: 1024, Threads: 1},
+			wantErr:    true,
+		},
+		{
+			name:   "Ethash at the announced epoch",
+			algo:   model.AlgorithmEthash,
+			params: &model.PowParams{Epoch: 490000, CacheSize: 1024, DatasetSize: 32 * 1024},
+		},
+		{
+			name:    "Argon2id memory above the limit",
+			algo:    model.AlgorithmArgon2id,
+			params:  &model.PowParams{Time: 1, Memory: 4 << 20, Threads: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Scrypt memory above the limit",
+			algo:    model.AlgorithmScrypt,
+			params:  &model.PowParams{N: 1 << 20, R: 8, P: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Scrypt with huge N and r",
+			algo:    model.AlgorithmScrypt,
+			params:  &model.PowParams{N: 1 << 40, R: 1 << 30, P: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Ethash dataset above the limit",
+			algo:    model.AlgorithmEthash,
+			params:  &model.PowParams{Epoch: 490000, CacheSize: 1024, DatasetSize: 4 << 30},
+			wantErr: true,
+		},
+		{
+			name:    "Ethash without dataset size",
+			algo:    model.AlgorithmEthash,
+			params:  &model.PowParams{Epoch: 490000, CacheSize: 1024},
+			wantErr: true,
+		},
+		{
+			name:   "Hashcash with SHA-256",
+			algo:   model.AlgorithmHashcash,
+			params: &model.PowParams{Hash: model.HashSHA256},
+		},
+		{
+			name:    "Hashcash with unknown hash",
+			algo:    model.AlgorithmHashcash,
+			params:  &model.PowParams{Hash: "md5"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid parameters",
+			algo:    model.AlgorithmScrypt,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChallenge(tt.algorithms...)
+			if _, err := c.Algorithm(tt.algo, tt.params); (err != nil) != tt.wantErr {
+				t.Errorf("Challenge.Algorithm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChallenge_GenerateSolution(t *testing.T) {
+	t.Parallel()
+
+	c := NewChallenge()
+	algorithm, err := c.Algorithm(model.AlgorithmArgon2id, &model.PowParams{Time: 1, Memory: 64, Threads: 1})
+	if err != nil {
+		t.Fatalf("Challenge.Algorithm() error = %v", err)
+	}
+
+	solution, err := c.GenerateSolution(context.Background(), "challenge", algorithm, 4)
+	if err != nil {
+		t.Fatalf("Challenge.GenerateSolution() error = %v", err)
+	}
+	nonce, err := strconv.ParseUint(solution, 10, 64)
+	if err != nil {
+		t.Fatalf("Challenge.GenerateSolution() = %q, not a nonce", solution)
+	}
+	target := new(big.Int).Lsh(big.NewInt(1), 256-4)
+	if new(big.Int).SetBytes(algorithm.Hash("challenge", nonce)).Cmp(target) != -1 {
+		t.Errorf("Challenge.GenerateSolution() = %d, hash is above the target", nonce)
+	}
+}
+
+func TestChallenge_GenerateSolution_hashcash(t *testing.T) {
+	t.Parallel()
+
+	c := NewChallenge()
+	algorithm, err := c.Algorithm(model.AlgorithmHashcash, &model.PowParams{Hash: model.HashSHA1})
+	if err != nil {
+		t.Fatalf("Challenge.Algorithm() error = %v", err)
+	}
+
+	stamp, err := c.GenerateSolution(context.Background(), "uid", algorithm, 8)
+	if err != nil {
+		t.Fatalf("Challenge.GenerateSolution() error = %v", err)
+	}
+	hashcash, _ := pow.NewHashcash(model.HashSHA1, time.Minute)
+	if err := hashcash.Verify(stamp, "uid", 8, time.Now()); err != nil {
+		t.Errorf("Challenge.GenerateSolution() = %s, error = %v", stamp, err)
+	}
+}
+
+func TestChallenge_GenerateSolution_canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := NewChallenge()
+	if _, err := c.GenerateSolution(ctx, "challenge", pow.Keccak{}, 256); !errors.Is(err, context.Canceled) {
+		t.Errorf("Challenge.GenerateSolution() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestChallenge_Algorithms(t *testing.T) {
+	t.Parallel()
+
+	c := NewChallenge()
+	if got := c.Algorithms(); !reflect.DeepEqual(got, solverNames) {
+		t.Errorf("Challenge.Algorithms() = %v, want all known %v", got, solverNames)
+	}
+
+	c = NewChallenge(model.AlgorithmScrypt, model.AlgorithmKeccak)
+	want := []string{model.AlgorithmScrypt, model.AlgorithmKeccak}
+	if got := c.Algorithms(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Challenge.Algorithms() = %v, want %v", got, want)
+	}
+}
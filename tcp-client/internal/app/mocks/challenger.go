@@ -5,7 +5,10 @@ package mocks
 import (
 	context "context"
 
+	model "github.com/pullya/wow_tcp_server/tcp-client/internal/model"
 	mock "github.com/stretchr/testify/mock"
+
+	pow "github.com/pullya/wow_tcp_server/shared/pow"
 )
 
 // Challenger is an autogenerated mock type for the Challenger type
@@ -13,27 +16,82 @@ type Challenger struct {
 	mock.Mock
 }
 
-// GenerateSolution provides a mock function with given fields: ctx, challenge
-func (_m *Challenger) GenerateSolution(ctx context.Context, challenge string) string {
-	ret := _m.Called(ctx, challenge)
+// Algorithm provides a mock function with given fields: name, params
+func (_m *Challenger) Algorithm(name string, params *model.PowParams) (pow.Algorithm, error) {
+	ret := _m.Called(name, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Algorithm")
+	}
+
+	var r0 pow.Algorithm
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, *model.PowParams) (pow.Algorithm, error)); ok {
+		return rf(name, params)
+	}
+	if rf, ok := ret.Get(0).(func(string, *model.PowParams) pow.Algorithm); ok {
+		r0 = rf(name, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pow.Algorithm)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, *model.PowParams) error); ok {
+		r1 = rf(name, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Algorithms provides a mock function with given fields:
+func (_m *Challenger) Algorithms() []string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Algorithms")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// GenerateSolution provides a mock function with given fields: ctx, challenge, algorithm, difficulty
+func (_m *Challenger) GenerateSolution(ctx context.Context, challenge string, algorithm pow.Algorithm, difficulty int) (string, error) {
+	ret := _m.Called(ctx, challenge, algorithm, difficulty)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GenerateSolution")
 	}
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
-		r0 = rf(ctx, challenge)
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, pow.Algorithm, int) (string, error)); ok {
+		return rf(ctx, challenge, algorithm, difficulty)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, pow.Algorithm, int) string); ok {
+		r0 = rf(ctx, challenge, algorithm, difficulty)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	return r0
-}
+	if rf, ok := ret.Get(1).(func(context.Context, string, pow.Algorithm, int) error); ok {
+		r1 = rf(ctx, challenge, algorithm, difficulty)
+	} else {
+		r1 = ret.Error(1)
+	}
 
-// SetDifficulty provides a mock function with given fields: diff
-func (_m *Challenger) SetDifficulty(diff int) {
-	_m.Called(diff)
+	return r0, r1
 }
 
 // NewChallenger creates a new instance of Challenger. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
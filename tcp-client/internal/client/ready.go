@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-client/internal/config"
+)
+
+// WaitReady polls url every interval until it answers 200 OK or timeout passes.
+func WaitReady(ctx context.Context, url string, timeout time.Duration, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := checkReady(ctx, url)
+		if err == nil {
+			return nil
+		}
+		config.Logger.Debugf("Server is not ready yet: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("server is not ready after %s: %w", timeout, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+func checkReady(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return nil
+}
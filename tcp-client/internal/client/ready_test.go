@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-client/internal/config"
+)
+
+func TestWaitReady(t *testing.T) {
+	config.InitLogger()
+
+	var probes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" {
+			http.NotFound(w, r)
+			return
+		}
+		// the server becomes ready on the third probe
+		if probes.Add(1) < 3 {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		timeout time.Duration
+		wantErr bool
+	}{
+		{
+			name:    "Ready after retries",
+			url:     srv.URL + "/readyz",
+			timeout: 5 * time.Second,
+			wantErr: false,
+		},
+		{
+			name:    "Timeout",
+			url:     srv.URL + "/missing",
+			timeout: 50 * time.Millisecond,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := WaitReady(context.Background(), tt.url, tt.timeout, 10*time.Millisecond); (err != nil) != tt.wantErr {
+				t.Errorf("WaitReady() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
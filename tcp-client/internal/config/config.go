@@ -2,9 +2,11 @@ package config
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -27,6 +29,30 @@ const (
 	envClientsCount = "WOW_CLIENT_CLIENTS_COUNT"
 	envConnInterval = "WOW_CLIENT_CONN_INTERVAL"
 	envLogLevel     = "WOW_CLIENT_LOG_LEVEL"
+	envMode         = "WOW_CLIENT_MODE"
+	envTags         = "WOW_CLIENT_TAGS"
+	envAuthor       = "WOW_CLIENT_AUTHOR"
+	envLanguage     = "WOW_CLIENT_LANGUAGE"
+	envClientID     = "WOW_CLIENT_ID"
+	envDaily        = "WOW_CLIENT_DAILY"
+	envReadinessURL = "WOW_CLIENT_READINESS_URL"
+	envReadinessTTL = "WOW_CLIENT_READINESS_TIMEOUT"
+	envTracingExp   = "WOW_CLIENT_TRACING_EXPORTER"
+	envTracingURL   = "WOW_CLIENT_TRACING_ENDPOINT"
+	envAlgorithms   = "WOW_CLIENT_POW_ALGORITHMS"
+
+	ModeSession = "session"
+	ModeLegacy  = "legacy"
+
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+
+	AlgorithmKeccak   = "keccak"
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmScrypt   = "scrypt"
+	AlgorithmEthash   = "ethash"
+	AlgorithmHashcash = "hashcash"
 )
 
 var Config Configuration
@@ -47,6 +73,17 @@ var envArray = []string{
 	envClientsCount,
 	envConnInterval,
 	envLogLevel,
+	envMode,
+	envTags,
+	envAuthor,
+	envLanguage,
+	envClientID,
+	envDaily,
+	envReadinessURL,
+	envReadinessTTL,
+	envTracingExp,
+	envTracingURL,
+	envAlgorithms,
 }
 
 type LogLevel string
@@ -54,10 +91,25 @@ type LogLevel string
 type Configuration struct {
 	Port        int    `yaml:"port"`
 	ServiceName string `yaml:"serviceName"`
+	Mode        string `yaml:"mode"`
 
 	ClientsCount int           `yaml:"clientsCount"`
 	ConnInterval time.Duration `yaml:"connInterval"`
 
+	Tags     []string `yaml:"tags"`
+	Author   string   `yaml:"author"`
+	Language string   `yaml:"language"`
+	ClientID string   `yaml:"clientId"`
+	Daily    bool     `yaml:"daily"`
+
+	ReadinessURL     string `yaml:"readinessUrl"`
+	ReadinessTimeout int    `yaml:"readinessTimeout"`
+
+	TracingExporter string `yaml:"tracingExporter"`
+	TracingEndpoint string `yaml:"tracingEndpoint"`
+
+	PowAlgorithms []string `yaml:"powAlgorithms"`
+
 	LogLevel LogLevel `yaml:"logLevel"`
 }
 
@@ -79,6 +131,14 @@ func ReadConfig() {
 	log.Debugf("Default configuration read: %v", Config)
 
 	checkEnv()
+
+	// config.yaml isn't checked by checkEnv, so a typo would silently fall back to legacy
+	if Config.Mode == "" {
+		Config.Mode = ModeLegacy
+	}
+	if _, err := validateMode(Config.Mode); err != nil {
+		log.Fatalf("invalid mode '%s', error: %v", Config.Mode, err)
+	}
 }
 
 func (l LogLevel) ToLogrusFormat() log.Level {
@@ -121,6 +181,56 @@ func checkEnv() {
 					Config.LogLevel = ll
 					log.Debugf("logLevel set to '%v'", Config.LogLevel)
 				}
+			case envMode:
+				mode, err := validateMode(envVal)
+				if err == nil {
+					Config.Mode = mode
+					log.Debugf("mode set to '%s'", Config.Mode)
+				}
+			case envTags:
+				tags, err := validateTags(envVal)
+				if err == nil {
+					Config.Tags = tags
+					log.Debugf("tags set to %v", Config.Tags)
+				}
+			case envAuthor:
+				Config.Author = envVal
+				log.Debugf("author set to '%s'", Config.Author)
+			case envLanguage:
+				Config.Language = envVal
+				log.Debugf("language set to '%s'", Config.Language)
+			case envClientID:
+				Config.ClientID = envVal
+				log.Debugf("clientId set to '%s'", Config.ClientID)
+			case envDaily:
+				if daily, err := strconv.ParseBool(envVal); err == nil {
+					Config.Daily = daily
+					log.Debugf("daily set to %v", Config.Daily)
+				}
+			case envReadinessURL:
+				Config.ReadinessURL = envVal
+				log.Debugf("readinessUrl set to '%s'", Config.ReadinessURL)
+			case envReadinessTTL:
+				timeout, err := validateConnInterval(envVal)
+				if err == nil {
+					Config.ReadinessTimeout = timeout
+					log.Debugf("readinessTimeout set to %d", Config.ReadinessTimeout)
+				}
+			case envTracingExp:
+				exporter, err := validateTracingExporter(envVal)
+				if err == nil {
+					Config.TracingExporter = exporter
+					log.Debugf("tracingExporter set to '%s'", Config.TracingExporter)
+				}
+			case envTracingURL:
+				Config.TracingEndpoint = envVal
+				log.Debugf("tracingEndpoint set to '%s'", Config.TracingEndpoint)
+			case envAlgorithms:
+				algorithms, err := validateAlgorithms(envVal)
+				if err == nil {
+					Config.PowAlgorithms = algorithms
+					log.Debugf("powAlgorithms set to %v", Config.PowAlgorithms)
+				}
 			}
 		}
 	}
@@ -166,6 +276,71 @@ func validateLogLevel(in string) (LogLevel, error) {
 	return LogLevel(in), nil
 }
 
+func validateMode(in string) (string, error) {
+	if in != ModeSession && in != ModeLegacy {
+		return "", errors.New("incorrect mode")
+	}
+	return in, nil
+}
+
+func validateTracingExporter(in string) (string, error) {
+	if in != TracingExporterNone && in != TracingExporterStdout && in != TracingExporterOTLP {
+		return "", errors.New("incorrect tracing exporter")
+	}
+	return in, nil
+}
+
+// validateTags splits a comma-separated tag list.
+func validateTags(in string) ([]string, error) {
+	var tags []string
+	for _, tag := range strings.Split(in, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return nil, errors.New("empty tag")
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// validateAlgorithms splits a comma-separated list of pow algorithms.
+func validateAlgorithms(in string) ([]string, error) {
+	var algorithms []string
+	for _, algorithm := range strings.Split(in, ",") {
+		algorithm = strings.TrimSpace(algorithm)
+		if algorithm != AlgorithmKeccak && algorithm != AlgorithmArgon2id && algorithm != AlgorithmScrypt && algorithm != AlgorithmEthash && algorithm != AlgorithmHashcash {
+			return nil, errors.New("incorrect pow algorithm")
+		}
+		algorithms = append(algorithms, algorithm)
+	}
+	return algorithms, nil
+}
+
+// ParseFlags overrides the quote request with command line flags,
+// which take precedence over the config file and environment.
+func ParseFlags(args []string) error {
+	fs := flag.NewFlagSet(Config.ServiceName, flag.ContinueOnError)
+	tags := fs.String("tags", strings.Join(Config.Tags, ","), "comma-separated tags the quote must have")
+	fs.StringVar(&Config.Author, "author", Config.Author, "quote author")
+	fs.StringVar(&Config.Language, "language", Config.Language, "quote language")
+	fs.BoolVar(&Config.Daily, "daily", Config.Daily, "request the quote of the day, ignoring the filter")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *tags == "" {
+		Config.Tags = nil
+		return nil
+	}
+	parsed, err := validateTags(*tags)
+	if err != nil {
+		return err
+	}
+	Config.Tags = parsed
+	return nil
+}
+
 func BuildAddress(port int) string {
 	return fmt.Sprintf("tcp_server:%d", port)
 }
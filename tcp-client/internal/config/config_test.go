@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -171,3 +172,240 @@ func Test_validateLogLevel(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateMode(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 session",
+			args:    args{in: "session"},
+			want:    ModeSession,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 legacy",
+			args:    args{in: "legacy"},
+			want:    ModeLegacy,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown",
+			args:    args{in: "stateful"},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 capital",
+			args:    args{in: "Session"},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateMode(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateMode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateTracingExporter(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 none",
+			args:    args{in: "none"},
+			want:    TracingExporterNone,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 stdout",
+			args:    args{in: "stdout"},
+			want:    TracingExporterStdout,
+			wantErr: false,
+		},
+		{
+			name:    "Success #3 otlp",
+			args:    args{in: "otlp"},
+			want:    TracingExporterOTLP,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown",
+			args:    args{in: "zipkin"},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 capital",
+			args:    args{in: "OTLP"},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateTracingExporter(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateTracingExporter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateTracingExporter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateTags(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 single",
+			args:    args{in: "life"},
+			want:    []string{"life"},
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 spaces",
+			args:    args{in: "life, time ,luck"},
+			want:    []string{"life", "time", "luck"},
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 empty tag",
+			args:    args{in: "life,,time"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 trailing comma",
+			args:    args{in: "life,"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateTags(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateTags() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateAlgorithms(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 single",
+			args:    args{in: "keccak"},
+			want:    []string{AlgorithmKeccak},
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 spaces",
+			args:    args{in: "argon2id, scrypt,ethash"},
+			want:    []string{AlgorithmArgon2id, AlgorithmScrypt, AlgorithmEthash},
+			wantErr: false,
+		},
+		{
+			name:    "Success #3 hashcash",
+			args:    args{in: "hashcash"},
+			want:    []string{AlgorithmHashcash},
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown",
+			args:    args{in: "keccak,sha256"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 empty",
+			args:    args{in: "keccak,"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateAlgorithms(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAlgorithms() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateAlgorithms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	Config = Configuration{Tags: []string{"life"}, Author: "Seneca", Language: "en"}
+	defer func() { Config = Configuration{} }()
+
+	if err := ParseFlags([]string{"-tags", "time, luck", "-language", "la", "-daily"}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+
+	want := Configuration{Tags: []string{"time", "luck"}, Author: "Seneca", Language: "la", Daily: true}
+	if !reflect.DeepEqual(Config, want) {
+		t.Errorf("ParseFlags() config = %+v, want %+v", Config, want)
+	}
+
+	if err := ParseFlags([]string{"-tags", ""}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	if Config.Tags != nil {
+		t.Errorf("ParseFlags() tags = %v, want none", Config.Tags)
+	}
+
+	if err := ParseFlags([]string{"-tags", "life,,time"}); err == nil {
+		t.Errorf("ParseFlags() error = nil, want error for empty tag")
+	}
+}
@@ -3,6 +3,8 @@ package model
 import (
 	"encoding/json"
 	"errors"
+
+	"github.com/pullya/wow_tcp_server/shared/pow"
 )
 
 const (
@@ -10,6 +12,23 @@ const (
 	MessageTypeWow       = "wow"
 	MessageTypeSolution  = "solution"
 	MessageTypeRequest   = "request"
+	MessageTypeError     = "error"
+	MessageTypeDaily     = "daily"
+)
+
+// Proof of work algorithms, a challenge without one is keccak.
+const (
+	AlgorithmKeccak   = pow.AlgorithmKeccak
+	AlgorithmArgon2id = pow.AlgorithmArgon2id
+	AlgorithmScrypt   = pow.AlgorithmScrypt
+	AlgorithmEthash   = pow.AlgorithmEthash
+	AlgorithmHashcash = pow.AlgorithmHashcash
+)
+
+// Hash functions of Hashcash stamps.
+const (
+	HashSHA1   = pow.HashSHA1
+	HashSHA256 = pow.HashSHA256
 )
 
 var (
@@ -18,16 +37,29 @@ var (
 		MessageTypeChallenge: true,
 		MessageTypeWow:       true,
 		MessageTypeSolution:  true,
+		MessageTypeError:     true,
+		MessageTypeDaily:     true,
 	}
 )
 
 type Message struct {
-	RequestID     string `json:"request_id"`
-	MessageType   string `json:"message_type"`
-	MessageString string `json:"message_string"`
-	Difficulty    int    `json:"difficulty"`
+	RequestID     string            `json:"request_id"`
+	MessageType   string            `json:"message_type"`
+	MessageString string            `json:"message_string"`
+	Difficulty    int               `json:"difficulty"`
+	Quote         *Quote            `json:"quote,omitempty"`
+	Filter        *QuoteFilter      `json:"filter,omitempty"`
+	ClientID      string            `json:"client_id,omitempty"`
+	Trace         map[string]string `json:"trace,omitempty"`
+	Algorithm     string            `json:"algorithm,omitempty"`
+	Params        *PowParams        `json:"params,omitempty"`
+	// Algorithms are the pow algorithms a client supports, sent with its request.
+	Algorithms []string `json:"algorithms,omitempty"`
 }
 
+// PowParams are the cost parameters of the challenge algorithm, see pow.Params.
+type PowParams = pow.Params
+
 func PrepareMessage(rid string, mType string, mString string, d int) Message {
 	return Message{
 		RequestID:     rid,
@@ -82,6 +82,14 @@ func TestParseServerMessage(t *testing.T) {
 			want:    Message{MessageType: "solution", MessageString: "2", Difficulty: 5},
 			wantErr: false,
 		},
+		{
+			name: "Successfully parsed #3 quote",
+			args: args{message: "{\"request_id\":\"1q2w3e\",\"message_type\":\"wow\",\"message_string\":\"Be yourself\",\"difficulty\":0," +
+				"\"quote\":{\"id\":\"q1\",\"text\":\"Be yourself\",\"author\":\"Oscar Wilde\",\"tags\":[\"life\"]}}"},
+			want: Message{RequestID: "1q2w3e", MessageType: "wow", MessageString: "Be yourself",
+				Quote: &Quote{ID: "q1", Text: "Be yourself", Author: "Oscar Wilde", Tags: []string{"life"}}},
+			wantErr: false,
+		},
 		{
 			name:    "Failed to parse #1",
 			args:    args{message: "Some message"},
@@ -148,3 +156,35 @@ func Test_validateMessageType(t *testing.T) {
 		})
 	}
 }
+
+func TestQuote_String(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		quote Quote
+		want  string
+	}{
+		{
+			name:  "Text only",
+			quote: Quote{ID: "q1", Text: "Be yourself"},
+			want:  "Be yourself",
+		},
+		{
+			name:  "Author and source",
+			quote: Quote{Text: "Be yourself", Author: "Oscar Wilde", Source: "Letters"},
+			want:  "Be yourself - Oscar Wilde, Letters",
+		},
+		{
+			name:  "All fields",
+			quote: Quote{Text: "Be yourself", Author: "Oscar Wilde", Language: "en", Tags: []string{"life", "self"}},
+			want:  "Be yourself - Oscar Wilde [en] #life #self",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.quote.String(); got != tt.want {
+				t.Errorf("Quote.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
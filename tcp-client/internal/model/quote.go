@@ -0,0 +1,47 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Quote struct {
+	ID       string   `json:"id"`
+	Text     string   `json:"text"`
+	Author   string   `json:"author,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	Language string   `json:"language,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// QuoteFilter asks the server for a quote with every listed tag,
+// the given author and language. Empty fields match any quote.
+type QuoteFilter struct {
+	Tags     []string `json:"tags,omitempty"`
+	Author   string   `json:"author,omitempty"`
+	Language string   `json:"language,omitempty"`
+}
+
+func (f QuoteFilter) IsEmpty() bool {
+	return len(f.Tags) == 0 && f.Author == "" && f.Language == ""
+}
+
+func (q Quote) String() string {
+	var b strings.Builder
+	b.WriteString(q.Text)
+	switch {
+	case q.Author != "" && q.Source != "":
+		fmt.Fprintf(&b, " - %s, %s", q.Author, q.Source)
+	case q.Author != "":
+		fmt.Fprintf(&b, " - %s", q.Author)
+	case q.Source != "":
+		fmt.Fprintf(&b, " - %s", q.Source)
+	}
+	if q.Language != "" {
+		fmt.Fprintf(&b, " [%s]", q.Language)
+	}
+	for _, tag := range q.Tags {
+		fmt.Fprintf(&b, " #%s", tag)
+	}
+	return b.String()
+}
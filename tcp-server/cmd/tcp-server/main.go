@@ -2,15 +2,26 @@ package main
 
 import (
 	"context"
+	"math/rand"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
+	"github.com/pullya/wow_tcp_server/shared/pow"
+	"github.com/pullya/wow_tcp_server/shared/tracing"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/admin"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/app"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/health"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/metrics"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/reputation"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/server"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/storage"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/token"
+	"github.com/redis/go-redis/v9"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -21,6 +32,10 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		healthcheck()
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	sigCh := make(chan os.Signal, 1)
@@ -33,18 +48,263 @@ func main() {
 		cancel()
 	}()
 
+	if config.Config.MetricsEnabled {
+		go func() {
+			if err := metrics.Run(ctx, config.Config.MetricsAddress); err != nil {
+				config.Logger.Fatalf("Error while starting metrics endpoint: %v", err)
+			}
+		}()
+	}
+
+	shutdownTracing, err := tracing.Init(ctx, config.Config.ServiceName, config.Config.TracingExporter, config.Config.TracingEndpoint)
+	if err != nil {
+		config.Logger.Fatalf("Error while initializing tracing: %v", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			config.Logger.Errorf("Error while flushing traces: %v", err)
+		}
+	}()
+
 	server := server.New(config.BuildPort(config.Config.Port), time.Millisecond*time.Duration(config.Config.Timeout))
 
-	WOWstorage := storage.New(storage.WordsOfWisdom)
+	quotes := storage.DefaultQuotes()
+	if config.Config.QuotesFile != "" {
+		var err error
+		quotes, err = storage.LoadQuotes(config.Config.QuotesFile)
+		if err != nil {
+			config.Logger.Fatalf("Error while loading quotes: %v", err)
+		}
+		config.Logger.Infof("Loaded %d quotes from %s", len(quotes), config.Config.QuotesFile)
+	}
+	WOWstorage := storage.New(quotes)
+	var quoteStorage storage.Storageer = WOWstorage
+	var quoteAdmin storage.QuoteAdmin = WOWstorage
+
+	if config.Config.QuoteStorage == config.QuoteStorageSQLite {
+		if config.Config.QuoteRotation {
+			config.Logger.Fatal("quoteRotation is supported only with the memory quote storage")
+		}
 
-	challenge := app.NewChallenge(config.Config.Difficulty)
+		sqliteStorage, err := storage.NewSQLiteStorage(config.Config.QuoteStoragePath)
+		if err != nil {
+			config.Logger.Fatalf("Error while opening quote storage: %v", err)
+		}
+		defer sqliteStorage.Close()
+		go sqliteStorage.RunUsageFlusher(ctx)
 
-	requeststore := storage.NewRequestStore(storage.ShardKey)
+		count, err := sqliteStorage.Count(ctx)
+		if err != nil {
+			config.Logger.Fatalf("Error while reading quote storage: %v", err)
+		}
+		if count == 0 {
+			if err := sqliteStorage.Import(ctx, quotes); err != nil {
+				config.Logger.Fatalf("Error while seeding quote storage: %v", err)
+			}
+			count = len(quotes)
+			config.Logger.Infof("Quote storage '%s' seeded with %d quotes", config.Config.QuoteStoragePath, count)
+		}
+		config.Logger.Infof("Serving %d quotes from '%s'", count, config.Config.QuoteStoragePath)
+		quoteStorage = sqliteStorage
+		quoteAdmin = sqliteStorage
+	} else if config.Config.QuotesFile != "" {
+		hupCh := make(chan os.Signal, 1)
+		signal.Notify(hupCh, syscall.SIGHUP)
 
-	app := app.New(&server, WOWstorage, requeststore, challenge)
+		reloader := storage.NewQuotesReloader(WOWstorage, config.Config.QuotesFile)
+		go reloader.Run(ctx, time.Millisecond*time.Duration(config.Config.QuotesReloadInterval), hupCh)
+	}
 
-	err := app.Run(ctx)
+	algorithms, err := newRegistry()
+	if err != nil {
+		config.Logger.Fatalf("failed to set up pow algorithms: %v", err)
+	}
+	config.Logger.Infof("Issuing challenges with pow algorithms %v", algorithms.Names())
+
+	var challenge app.Challenger = app.NewChallenge(config.Config.Difficulty, algorithms)
+	var adaptive *app.AdaptiveChallenge
+	if config.Config.AdaptiveDifficulty {
+		if config.Config.MinDifficulty > config.Config.MaxDifficulty {
+			config.Logger.Fatal("minDifficulty must not be greater than maxDifficulty")
+		}
+		if config.Config.DifficultyInterval <= 0 {
+			config.Logger.Fatal("difficultyInterval must be positive")
+		}
+		adaptive = app.NewAdaptiveChallenge(config.Config.MinDifficulty, config.Config.MaxDifficulty, config.Config.Difficulty, app.LoadLimits{
+			Connections: int64(config.Config.LoadMaxConnections),
+			AcceptRate:  config.Config.LoadMaxAcceptRate,
+			CPU:         config.Config.LoadMaxCPU,
+		}, config.Config.DifficultySmoothing, algorithms)
+		challenge = adaptive
+	}
+
+	challengeTTL := time.Millisecond * time.Duration(config.Config.ChallengeTTL)
+	solvedRetention := time.Millisecond * time.Duration(config.Config.SolvedRetention)
+	sweepInterval := time.Millisecond * time.Duration(config.Config.SweepInterval)
+
+	if config.Config.QuoteRotation {
+		rotating := storage.NewRotatingStorage(WOWstorage, config.Config.RotationHistorySize, config.Config.RotationMaxClients,
+			time.Millisecond*time.Duration(config.Config.RotationTTL), rand.NewSource(time.Now().UnixNano()))
+
+		go rotating.RunSweeper(ctx, sweepInterval)
+		quoteStorage = rotating
+	}
+
+	var requeststore storage.Requester
+	switch config.Config.RequestStore {
+	case config.RequestStoreBolt:
+		boltStore, err := storage.NewBoltRequestStore(config.Config.RequestStorePath, challengeTTL, solvedRetention)
+		if err != nil {
+			config.Logger.Fatalf("Error while opening request store: %v", err)
+		}
+		defer boltStore.Close()
+
+		go boltStore.RunSweeper(ctx, sweepInterval)
+		requeststore = boltStore
+	case config.RequestStoreRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     config.Config.RedisAddress,
+			Password: config.Config.RedisPassword,
+		})
+		defer client.Close()
+
+		if err := client.Ping(ctx).Err(); err != nil {
+			config.Logger.Fatalf("Error while connecting to redis: %v", err)
+		}
+		requeststore = storage.NewRedisRequestStore(client, config.Config.RedisKeyPrefix, challengeTTL, solvedRetention)
+	default:
+		memoryStore := storage.NewRequestStore(config.Config.ShardsCnt, storage.ShardKey, challengeTTL, solvedRetention)
+
+		go memoryStore.RunSweeper(ctx, sweepInterval)
+		requeststore = memoryStore
+	}
+
+	var signer *token.Signer
+	if config.Config.ChallengeMode == config.ChallengeModeSigned {
+		if config.Config.ChallengeSecret == "" {
+			config.Logger.Fatal("challengeSecret is required for signed challenges")
+		}
+		signer = token.New([]byte(config.Config.ChallengeSecret), challengeTTL)
+	}
+
+	var replay storage.ReplayDetector = requeststore
+	if config.Config.ReplayDetector == config.ReplayDetectorBloom {
+		bloomWindow := time.Millisecond * time.Duration(config.Config.BloomWindow)
+		if bloomWindow < challengeTTL {
+			config.Logger.Fatal("bloomWindow must not be shorter than challengeTTL")
+		}
+		bloom := storage.NewBloomReplayDetector(config.Config.BloomCapacity, config.Config.BloomFalsePositiveRate, bloomWindow)
+		metrics.ReplayFilter(func() (float64, float64) {
+			stats := bloom.Stats()
+			return stats.FillRatio, stats.EstimatedFalsePosRate
+		})
+		replay = bloom
+	} else if signer != nil && solvedRetention < challengeTTL {
+		config.Logger.Fatal("solvedRetention must not be shorter than challengeTTL for signed challenges")
+	}
+
+	var tracker *reputation.Tracker
+	var scores admin.ReputationProvider
+	if config.Config.ReputationEnabled {
+		tracker = reputation.New(reputation.Policy{
+			Request:      config.Config.ReputationRequestScore,
+			Solved:       config.Config.ReputationSolvedScore,
+			Failed:       config.Config.ReputationFailedScore,
+			Malformed:    config.Config.ReputationMalformedScore,
+			HalfLife:     time.Millisecond * time.Duration(config.Config.ReputationHalfLife),
+			PointsPerBit: config.Config.ReputationPointsPerBit,
+			MaxPenalty:   config.Config.ReputationMaxPenalty,
+			MaxBonus:     config.Config.ReputationMaxBonus,
+			SubnetWeight: config.Config.ReputationSubnetWeight,
+			IPv4Prefix:   config.Config.ReputationIPv4Prefix,
+			IPv6Prefix:   config.Config.ReputationIPv6Prefix,
+		})
+		go tracker.RunSweeper(ctx, sweepInterval)
+		scores = tracker
+	}
+
+	app := app.New(&server, quoteStorage, requeststore, challenge, signer, replay, tracker)
+
+	if adaptive != nil {
+		go adaptive.Run(ctx, time.Millisecond*time.Duration(config.Config.DifficultyInterval), app.Load)
+	}
+
+	if config.Config.HealthAddress != "" {
+		healthServer := health.New(config.Config.HealthAddress, app.Ready)
+		go func() {
+			if err := healthServer.Run(ctx); err != nil {
+				config.Logger.Fatalf("Error while starting health probes: %v", err)
+			}
+		}()
+	}
+
+	if config.Config.AdminEnabled {
+		if config.Config.AdminToken == "" {
+			config.Logger.Fatal("adminToken is required for the admin API")
+		}
+		adminServer := admin.New(config.Config.AdminAddress, config.Config.AdminToken, quoteAdmin, requeststore, &app, scores)
+		go func() {
+			if err := adminServer.Run(ctx); err != nil {
+				config.Logger.Fatalf("Error while starting admin API: %v", err)
+			}
+		}()
+	}
+
+	err = app.Run(ctx)
 	if err != nil {
 		config.Logger.Fatalf("Error while starting service: %v", err)
 	}
 }
+
+// healthcheck exits with 0 if the server started with the same config is ready.
+func healthcheck() {
+	if config.Config.HealthAddress == "" {
+		config.Logger.Fatal("healthAddress is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	err := health.Check(ctx, health.URL(config.Config.HealthAddress, health.ReadinessPath))
+	cancel()
+	if err != nil {
+		config.Logger.Fatalf("Server is not ready: %v", err)
+	}
+	os.Exit(0)
+}
+
+// newRegistry sets up the configured pow algorithms in preference order, keccak if none are.
+func newRegistry() (*pow.Registry, error) {
+	names := config.Config.PowAlgorithms
+	if len(names) == 0 {
+		names = []string{config.AlgorithmKeccak}
+	}
+
+	registry := pow.NewRegistry()
+	for _, name := range names {
+		var algorithm pow.Algorithm
+		var err error
+		switch name {
+		case config.AlgorithmEthash:
+			// the server only verifies, so it keeps the cache and never builds the dataset
+			algorithm, err = pow.NewEthash(config.Config.EthashCacheSize, config.Config.EthashDatasetSize,
+				time.Millisecond*time.Duration(config.Config.EthashEpochLength), false)
+		case config.AlgorithmHashcash:
+			algorithm, err = pow.NewHashcash(config.Config.HashcashHash, time.Millisecond*time.Duration(config.Config.HashcashWindow))
+		default:
+			algorithm, err = pow.NewAlgorithm(name, model.PowParams{
+				Time:    uint32(config.Config.Argon2Time),
+				Memory:  uint32(config.Config.Argon2Memory),
+				Threads: uint8(config.Config.Argon2Threads),
+				N:       config.Config.ScryptN,
+				R:       config.Config.ScryptR,
+				P:       config.Config.ScryptP,
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(algorithm, config.Config.PowDifficultyOffsets[name])
+	}
+	return registry, nil
+}
@@ -0,0 +1,266 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/httpserver"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/reputation"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/storage"
+)
+
+// StateProvider reports the state of the running tcp-server.
+type StateProvider interface {
+	Difficulty() int
+	ActiveConnections() int64
+}
+
+// ReputationProvider reports the reputation of a client address.
+type ReputationProvider interface {
+	Score(address string) (reputation.Score, error)
+}
+
+// State is the response of GET /state.
+type State struct {
+	Difficulty        int                  `json:"difficulty"`
+	ActiveConnections int64                `json:"active_connections"`
+	Requests          storage.RequestStats `json:"requests"`
+}
+
+// Server is the admin HTTP API. Every request must carry the configured bearer token.
+type Server struct {
+	address  string
+	token    string
+	quotes   storage.QuoteAdmin
+	requests storage.Requester
+	state    StateProvider
+	scores   ReputationProvider
+}
+
+// New creates the API. scores may be nil when the reputation isn't tracked.
+func New(address string, token string, quotes storage.QuoteAdmin, requests storage.Requester, state StateProvider, scores ReputationProvider) *Server {
+	return &Server{
+		address:  address,
+		token:    token,
+		quotes:   quotes,
+		requests: requests,
+		state:    state,
+		scores:   scores,
+	}
+}
+
+// Run serves the API until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	config.Logger.Infof("Launching admin API on %s...", s.address)
+	return httpserver.Run(ctx, s.address, s.Handler())
+}
+
+// Handler serves
+//
+//	GET    /quotes             list quotes
+//	POST   /quotes             add a quote, the body is a quote object
+//	GET    /quotes/{id}        get a quote
+//	PUT    /quotes/{id}        replace a quote, the body is a quote object
+//	DELETE /quotes/{id}        delete a quote
+//	GET    /quotes/{id}/usage  how many times a quote was served, if the quote storage counts it
+//	GET    /state              difficulty, active connections and request store counts
+//	GET    /reputation/{ip}    score of a client address and its subnet
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/quotes", s.handleQuotes)
+	mux.HandleFunc("/quotes/", s.handleQuote)
+	mux.HandleFunc("/state", s.handleState)
+	mux.HandleFunc("/reputation/", s.handleReputation)
+	return s.authorize(mux)
+}
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleQuotes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		quotes, err := s.quotes.ListQuotes(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if quotes == nil {
+			quotes = []model.Quote{}
+		}
+		writeJSON(w, http.StatusOK, quotes)
+	case http.MethodPost:
+		var q model.Quote
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&q); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		added, err := s.quotes.AddQuote(r.Context(), q)
+		if err != nil {
+			writeError(w, quoteErrorStatus(err), err)
+			return
+		}
+		config.Logger.Infof("Quote '%s' added via admin API", added.ID)
+		writeJSON(w, http.StatusCreated, added)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *Server) handleQuote(w http.ResponseWriter, r *http.Request) {
+	id, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/quotes/"), "/")
+	switch {
+	case id == "":
+		writeError(w, http.StatusNotFound, storage.ErrQuoteNotFound)
+		return
+	case sub == "usage":
+		s.handleUsage(w, r, id)
+		return
+	case sub != "":
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		q, err := s.quotes.GetQuote(r.Context(), id)
+		if err != nil {
+			writeError(w, quoteErrorStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, q)
+	case http.MethodPut:
+		var q model.Quote
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&q); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if q.ID != "" && q.ID != id {
+			writeError(w, http.StatusBadRequest, errors.New("quote id doesn't match the path"))
+			return
+		}
+		q.ID = id
+
+		updated, err := s.quotes.UpdateQuote(r.Context(), q)
+		if err != nil {
+			writeError(w, quoteErrorStatus(err), err)
+			return
+		}
+		config.Logger.Infof("Quote '%s' updated via admin API", id)
+		writeJSON(w, http.StatusOK, updated)
+	case http.MethodDelete:
+		if err := s.quotes.DeleteQuote(r.Context(), id); err != nil {
+			writeError(w, quoteErrorStatus(err), err)
+			return
+		}
+		config.Logger.Infof("Quote '%s' deleted via admin API", id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	counter, ok := s.quotes.(storage.UsageCounter)
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("usage isn't counted by the quote storage"))
+		return
+	}
+
+	usage, err := counter.Usage(r.Context(), id)
+	if err != nil {
+		writeError(w, quoteErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	requests, err := s.requests.Stats(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, State{
+		Difficulty:        s.state.Difficulty(),
+		ActiveConnections: s.state.ActiveConnections(),
+		Requests:          requests,
+	})
+}
+
+func (s *Server) handleReputation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if s.scores == nil {
+		writeError(w, http.StatusNotFound, errors.New("reputation is disabled"))
+		return
+	}
+
+	score, err := s.scores.Score(strings.TrimPrefix(r.URL.Path, "/reputation/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, score)
+}
+
+func quoteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrInvalidQuote):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrQuoteExists):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrQuoteNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		config.Logger.Errorf("Error while writing admin response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,317 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/reputation"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/storage"
+)
+
+type stubState struct{}
+
+func (stubState) Difficulty() int          { return 20 }
+func (stubState) ActiveConnections() int64 { return 3 }
+
+func TestServer_Handler(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+
+	quotes := storage.New([]model.Quote{{ID: "1", Text: "Vivere est cogitare", Author: "Cicero"}})
+	requests := storage.NewRequestStore(1, func(string) uint32 { return 0 }, time.Minute, time.Minute)
+	requests.Add(ctx, "pending", "")
+	requests.Add(ctx, "solved", "")
+	if _, err := requests.Consume(ctx, "solved"); err != nil {
+		t.Fatalf("RequestStore.Consume() error = %v", err)
+	}
+
+	scores := reputation.New(reputation.Policy{Failed: 5, PointsPerBit: 5, MaxPenalty: 8, SubnetWeight: 0.5, IPv4Prefix: 24, IPv6Prefix: 64})
+	scores.Record("10.0.0.1", reputation.EventFailed)
+
+	handler := New("", "secret", quotes, requests, stubState{}, scores).Handler()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "No token",
+			method:     http.MethodGet,
+			path:       "/quotes",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"error":"unauthorized"}`,
+		},
+		{
+			name:       "Wrong token",
+			method:     http.MethodGet,
+			path:       "/state",
+			token:      "guess",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"error":"unauthorized"}`,
+		},
+		{
+			name:       "State",
+			method:     http.MethodGet,
+			path:       "/state",
+			token:      "secret",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"difficulty":20,"active_connections":3,"requests":{"outstanding":1,"solved":1}}`,
+		},
+		{
+			name:       "Reputation",
+			method:     http.MethodGet,
+			path:       "/reputation/10.0.0.1",
+			token:      "secret",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"address":"10.0.0.1","subnet":"10.0.0.0/24","address_score":5,"subnet_score":5,"score":7.5,"adjustment":2}`,
+		},
+		{
+			name:       "Reputation of invalid address",
+			method:     http.MethodGet,
+			path:       "/reputation/localhost",
+			token:      "secret",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid address"}`,
+		},
+		{
+			name:       "Add quote",
+			method:     http.MethodPost,
+			path:       "/quotes",
+			token:      "secret",
+			body:       `{"id":"2","text":"Carpe diem","author":"Horace","tags":["time"]}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":"2","text":"Carpe diem","author":"Horace","tags":["time"]}`,
+		},
+		{
+			name:       "Add duplicate",
+			method:     http.MethodPost,
+			path:       "/quotes",
+			token:      "secret",
+			body:       `{"text":"carpe diem"}`,
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"error":"quote already exists"}`,
+		},
+		{
+			name:       "Add empty",
+			method:     http.MethodPost,
+			path:       "/quotes",
+			token:      "secret",
+			body:       `{"text":" "}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid quote: empty quote"}`,
+		},
+		{
+			name:       "Get quote",
+			method:     http.MethodGet,
+			path:       "/quotes/2",
+			token:      "secret",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"2","text":"Carpe diem","author":"Horace","tags":["time"]}`,
+		},
+		{
+			name:       "Get unknown",
+			method:     http.MethodGet,
+			path:       "/quotes/3",
+			token:      "secret",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"quote not found"}`,
+		},
+		{
+			name:       "Update quote",
+			method:     http.MethodPut,
+			path:       "/quotes/2",
+			token:      "secret",
+			body:       `{"text":"Carpe diem","author":"Horace","source":"Odes","tags":["time"]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"2","text":"Carpe diem","author":"Horace","source":"Odes","tags":["time"]}`,
+		},
+		{
+			name:       "Update with another id",
+			method:     http.MethodPut,
+			path:       "/quotes/2",
+			token:      "secret",
+			body:       `{"id":"1","text":"Carpe diem"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"quote id doesn't match the path"}`,
+		},
+		{
+			name:       "Update to duplicate",
+			method:     http.MethodPut,
+			path:       "/quotes/2",
+			token:      "secret",
+			body:       `{"text":"vivere est cogitare"}`,
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"error":"quote already exists"}`,
+		},
+		{
+			name:       "Update unknown",
+			method:     http.MethodPut,
+			path:       "/quotes/3",
+			token:      "secret",
+			body:       `{"text":"Something else"}`,
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"quote not found"}`,
+		},
+		{
+			name:       "Usage not counted",
+			method:     http.MethodGet,
+			path:       "/quotes/2/usage",
+			token:      "secret",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"usage isn't counted by the quote storage"}`,
+		},
+		{
+			name:       "Unknown quote path",
+			method:     http.MethodGet,
+			path:       "/quotes/2/other",
+			token:      "secret",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"not found"}`,
+		},
+		{
+			name:       "Delete quote",
+			method:     http.MethodDelete,
+			path:       "/quotes/1",
+			token:      "secret",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "Delete unknown",
+			method:     http.MethodDelete,
+			path:       "/quotes/1",
+			token:      "secret",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"quote not found"}`,
+		},
+		{
+			name:       "List quotes",
+			method:     http.MethodGet,
+			path:       "/quotes",
+			token:      "secret",
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"id":"2","text":"Carpe diem","author":"Horace","source":"Odes","tags":["time"]}]`,
+		},
+		{
+			name:       "Method not allowed",
+			method:     http.MethodPut,
+			path:       "/quotes",
+			token:      "secret",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   `{"error":"method not allowed"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_Usage(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+
+	quotes, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "quotes.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	defer quotes.Close()
+	if _, err := quotes.AddQuote(ctx, model.Quote{ID: "1", Text: "Vivere est cogitare"}); err != nil {
+		t.Fatalf("SQLiteStorage.AddQuote() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := quotes.GetRandomQuote(ctx, model.QuoteFilter{}); err != nil {
+			t.Fatalf("SQLiteStorage.GetRandomQuote() error = %v", err)
+		}
+	}
+
+	handler := New("", "secret", quotes, storage.NewRequestStore(1, func(string) uint32 { return 0 }, time.Minute, time.Minute), stubState{}, nil).Handler()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantServed int64
+	}{
+		{
+			name:       "Served quote",
+			path:       "/quotes/1/usage",
+			wantStatus: http.StatusOK,
+			wantServed: 2,
+		},
+		{
+			name:       "Unknown quote",
+			path:       "/quotes/2/usage",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var usage storage.QuoteUsage
+			if err := json.Unmarshal(rec.Body.Bytes(), &usage); err != nil {
+				t.Fatalf("body %s: %v", rec.Body, err)
+			}
+			if usage.Served != tt.wantServed || usage.LastServed.IsZero() {
+				t.Errorf("usage = %+v, want %d served", usage, tt.wantServed)
+			}
+		})
+	}
+}
+
+func TestServer_Run(t *testing.T) {
+	config.InitLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := New("127.0.0.1:0", "secret", storage.New(nil), storage.NewRequestStore(1, func(string) uint32 { return 0 }, time.Minute, time.Minute), stubState{}, nil)
+
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Server.Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server.Run() didn't stop after the context was canceled")
+	}
+}
@@ -2,24 +2,39 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/pullya/wow_tcp_server/shared/pow"
+	"github.com/pullya/wow_tcp_server/shared/tracing"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/metrics"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/reputation"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/server"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/storage"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/token"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var connCnt = 0
 
+var tracer = otel.Tracer("github.com/pullya/wow_tcp_server/tcp-server/internal/app")
+
+var ErrChallengeExpired = errors.New("challenge expired")
+
 type Apper interface {
 	doProofOfWork(ctx context.Context, id int) error
-	sendChallenge(ctx context.Context, conn net.Conn, id int) error
-	validatePOW(ctx context.Context, clientResponse model.Message, id int) error
-	sendWOW(ctx context.Context, conn net.Conn, uid string, id int) error
+	sendChallenge(ctx context.Context, conn net.Conn, req model.QuoteRequest, algorithms []string, id int) (string, error)
+	validatePOW(ctx context.Context, clientResponse model.Message, addr string, id int) (model.QuoteRequest, error)
+	sendWOW(ctx context.Context, conn net.Conn, uid string, req model.QuoteRequest, id int) error
 }
 
 type App struct {
@@ -27,14 +42,27 @@ type App struct {
 	storage      storage.Storageer
 	requeststore storage.Requester
 	challenge    Challenger
+	signer       *token.Signer
+	replay       storage.ReplayDetector
+	reputation   *reputation.Tracker
+
+	active   atomic.Int64
+	accepted atomic.Int64
+	ready    atomic.Bool
 }
 
-func New(tcpServer server.ServerProvider, storage storage.Storageer, requeststore storage.Requester, challenge Challenger) App {
+// New creates the application. With a nil signer issued challenges are kept in
+// requeststore, otherwise they are HMAC-signed and only solved ones are remembered by replay.
+// A non-nil reputation adjusts the difficulty to the behaviour of each client address.
+func New(tcpServer server.ServerProvider, storage storage.Storageer, requeststore storage.Requester, challenge Challenger, signer *token.Signer, replay storage.ReplayDetector, reputation *reputation.Tracker) App {
 	return App{
 		server:       tcpServer,
 		storage:      storage,
 		requeststore: requeststore,
 		challenge:    challenge,
+		signer:       signer,
+		replay:       replay,
+		reputation:   reputation,
 	}
 }
 
@@ -46,6 +74,9 @@ func (a *App) Run(ctx context.Context) error {
 	}
 	defer listener.Close()
 
+	a.ready.Store(true)
+	defer a.ready.Store(false)
+
 	config.Logger.Debug("Waiting for connections...")
 
 	for {
@@ -66,9 +97,50 @@ func (a *App) Run(ctx context.Context) error {
 	}
 }
 
+// Ready reports whether the listener is bound and connections are being accepted.
+func (a *App) Ready() bool {
+	return a.ready.Load()
+}
+
+// Difficulty is the number of leading zero bits required from the solutions.
+func (a *App) Difficulty() int {
+	return a.challenge.Difficulty()
+}
+
+// ActiveConnections is the number of connections being served right now.
+func (a *App) ActiveConnections() int64 {
+	return a.active.Load()
+}
+
+// Load samples the server load the adaptive difficulty follows.
+func (a *App) Load() Load {
+	busy, total := cpuTimes()
+	return Load{
+		At:       time.Now(),
+		Active:   a.active.Load(),
+		Accepted: a.accepted.Load(),
+		CPUBusy:  busy,
+		CPUTotal: total,
+	}
+}
+
 func (a *App) handleConnection(ctx context.Context, conn net.Conn, id int) {
+	acceptedAt := time.Now()
+	metrics.ConnectionsAccepted.Inc()
+	metrics.ConnectionsActive.Inc()
+	a.accepted.Add(1)
+	a.active.Add(1)
+	defer func() {
+		a.active.Add(-1)
+		metrics.ConnectionsActive.Dec()
+	}()
 	defer conn.Close()
 
+	if config.Config.Mode == config.ModeSession {
+		a.handleSession(ctx, conn, id)
+		return
+	}
+
 	if err := conn.SetReadDeadline(time.Now().Add(a.server.GetTimeout())); err != nil {
 		config.Logger.WithField("connection", id).Errorf("Error while setting timeout: %v", err)
 		return
@@ -84,22 +156,27 @@ func (a *App) handleConnection(ctx context.Context, conn net.Conn, id int) {
 
 	clientRequest, err := model.ParseServerMessage(request)
 	if err != nil {
+		a.record(clientAddress(conn), reputation.EventMalformed)
 		config.Logger.WithField("connection", id).Errorf("Unable to unmarshal client message: %v\n", err)
 		return
 	}
 
+	ctx, span := startConnectionSpan(ctx, conn, clientRequest, acceptedAt, id)
+	defer span.End()
+
 	switch clientRequest.MessageType {
-	case model.MessageTypeRequest:
-		if err := a.sendChallenge(ctx, conn, id); err != nil {
+	case model.MessageTypeRequest, model.MessageTypeDaily:
+		if _, err := a.sendChallenge(ctx, conn, quoteRequest(clientRequest), clientRequest.Algorithms, id); err != nil {
 			config.Logger.WithField("connection", id).Errorf("Error while sending challenge: %v", err)
 			return
 		}
 	case model.MessageTypeSolution:
-		if err := a.validatePOW(ctx, clientRequest, id); err != nil {
+		req, err := a.validatePOW(ctx, clientRequest, clientAddress(conn), id)
+		if err != nil {
 			config.Logger.WithField("connection", id).Errorf("Failed to validate POW: %v", err)
 			return
 		}
-		if err = a.sendWOW(ctx, conn, clientRequest.RequestID, id); err != nil {
+		if err = a.sendWOW(storage.WithClient(ctx, clientIdentity(conn, req)), conn, clientRequest.RequestID, req, id); err != nil {
 			return
 		}
 	default:
@@ -108,65 +185,432 @@ func (a *App) handleConnection(ctx context.Context, conn net.Conn, id int) {
 	}
 }
 
-func (a *App) sendChallenge(ctx context.Context, conn net.Conn, id int) error {
-	uid := storage.GenUID()
-	challengeMessage := model.PrepareMessage(uid, model.MessageTypeChallenge, generatePOWChallenge(uid), a.challenge.Difficulty())
+// handleSession serves request -> challenge -> solution -> wow over a single connection.
+// Every step has its own deadline, and the solution is accepted only for the challenge
+// issued on this connection.
+func (a *App) handleSession(ctx context.Context, conn net.Conn, id int) {
+	acceptedAt := time.Now()
+	clientRequest, err := a.receiveStep(ctx, conn, id)
+	if err != nil {
+		config.Logger.WithField("connection", id).Errorf("Error reading request: %v", err)
+		return
+	}
+
+	ctx, span := startConnectionSpan(ctx, conn, clientRequest, acceptedAt, id)
+	defer span.End()
+	if clientRequest.MessageType != model.MessageTypeRequest && clientRequest.MessageType != model.MessageTypeDaily {
+		config.Logger.WithField("connection", id).Errorf("Unexpected message type: %s", clientRequest.MessageType)
+		return
+	}
 
-	if err := a.server.SendMessage(ctx, conn, challengeMessage.AsJsonString()); err != nil {
-		return err
+	uid, err := a.sendChallenge(ctx, conn, quoteRequest(clientRequest), clientRequest.Algorithms, id)
+	if err != nil {
+		config.Logger.WithField("connection", id).Errorf("Error while sending challenge: %v", err)
+		return
 	}
 
-	a.requeststore.Add(ctx, uid)
+	// includes the time the client spends solving the challenge
+	_, await := tracer.Start(ctx, "await solution")
+	clientResponse, err := a.receiveStep(ctx, conn, id)
+	endSpan(await, err)
+	if err != nil {
+		config.Logger.WithField("connection", id).Errorf("Error reading solution: %v", err)
+		return
+	}
+	if clientResponse.MessageType != model.MessageTypeSolution {
+		config.Logger.WithField("connection", id).Errorf("Unexpected message type: %s", clientResponse.MessageType)
+		return
+	}
+	if clientResponse.RequestID != uid {
+		a.rejectSolution(clientAddress(conn), metrics.ReasonUnknownRequest, nil)
+		config.Logger.WithField("connection", id).Errorf("Solution for '%s' doesn't match issued challenge '%s'", clientResponse.RequestID, uid)
+		return
+	}
 
-	return nil
+	req, err := a.validatePOW(ctx, clientResponse, clientAddress(conn), id)
+	if err != nil {
+		config.Logger.WithField("connection", id).Errorf("Failed to validate POW: %v", err)
+		return
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(stepTimeout())); err != nil {
+		config.Logger.WithField("connection", id).Errorf("Error while setting timeout: %v", err)
+		return
+	}
+	if err := a.sendWOW(storage.WithClient(ctx, clientIdentity(conn, req)), conn, uid, req, id); err != nil {
+		return
+	}
 }
 
-func (a *App) validatePOW(ctx context.Context, clientResponse model.Message, id int) error {
-	ok, err := a.requeststore.Get(ctx, clientResponse.RequestID)
+func (a *App) receiveStep(ctx context.Context, conn net.Conn, id int) (model.Message, error) {
+	if err := conn.SetDeadline(time.Now().Add(stepTimeout())); err != nil {
+		return model.Message{}, errors.Wrap(err, "setting timeout")
+	}
+
+	message, err := a.server.ReceiveMessage(ctx, conn)
 	if err != nil {
-		config.Logger.WithField("connection", id).Errorf("Failed to find request '%s' in store: %v", clientResponse.RequestID, err)
-		return err
+		return model.Message{}, err
 	}
-	if ok {
-		config.Logger.WithField("connection", id).Errorf("This POW was already handled '%s'", clientResponse.RequestID)
-		return errors.New("Double work")
+
+	config.Logger.WithField("connection", id).Debugf("Message from client received: %s", message)
+
+	m, err := model.ParseServerMessage(message)
+	if err != nil {
+		a.record(clientAddress(conn), reputation.EventMalformed)
 	}
+	return m, err
+}
 
-	solution, err := clientResponse.GetUint64()
+// sendChallenge issues a challenge with the preferred algorithm among the ones the client
+// supports and remembers what quote was requested with it: in the request store or
+// inside the signed challenge.
+func (a *App) sendChallenge(ctx context.Context, conn net.Conn, req model.QuoteRequest, algorithms []string, id int) (uid string, err error) {
+	difficulty := a.challenge.Difficulty()
+	if a.reputation != nil {
+		difficulty = a.reputation.Adjust(clientAddress(conn), difficulty)
+		a.reputation.Record(clientAddress(conn), reputation.EventRequest)
+	}
+
+	ctx, span := tracer.Start(ctx, "issue challenge")
+	defer func() { endSpan(span, err) }()
+
+	algorithm, difficulty, err := a.challenge.Negotiate(algorithms, difficulty)
 	if err != nil {
+		config.Logger.WithField("connection", id).Warnf("Client supports none of the pow algorithms: %v", algorithms)
+		errMessage := model.PrepareMessage("", model.MessageTypeError, err.Error(), 0)
+		if err := a.server.SendMessage(ctx, conn, errMessage.AsJsonString()); err != nil {
+			config.Logger.WithField("connection", id).Errorf("Error while sending response: %v", err)
+		}
+		return "", err
+	}
+	span.SetAttributes(attribute.Int("wow.difficulty", difficulty), attribute.String("wow.algorithm", algorithm.Name()))
+	metrics.Difficulty.Set(float64(difficulty))
+
+	params := algorithm.Params()
+	issued := pow.Issued{Difficulty: difficulty}
+	// keccak is left out, so challenges stay as small as before negotiation
+	if algorithm.Name() != model.AlgorithmKeccak {
+		issued.Algorithm = algorithm.Name()
+	}
+	if params != nil {
+		issued.Epoch = params.Epoch
+	}
+
+	uid = storage.GenUID()
+	if a.signer != nil {
+		// signed challenges carry the difficulty in their claims
+		signedIssued := issued
+		signedIssued.Difficulty = 0
+		payload, err := encodeRequest(req, signedIssued)
+		if err != nil {
+			return "", err
+		}
+		signed, err := a.signer.Issue(clientAddress(conn), difficulty, payload)
+		if err != nil {
+			return "", err
+		}
+		uid = signed
+	}
+
+	challenge := generatePOWChallenge(uid)
+	if _, ok := algorithm.(pow.Stamper); ok {
+		// a stamp is bound to the challenge by its resource
+		challenge = uid
+	}
+	challengeMessage := model.PrepareMessage(uid, model.MessageTypeChallenge, challenge, difficulty)
+	challengeMessage.Algorithm = algorithm.Name()
+	challengeMessage.Params = params
+
+	if err := a.server.SendMessage(ctx, conn, challengeMessage.AsJsonString()); err != nil {
+		return "", err
+	}
+
+	if a.signer == nil {
+		// an unsigned client ID could be changed on every request to get a fresh quote history
+		req.ClientID = ""
+		payload, err := encodeRequest(req, issued)
+		if err != nil {
+			return "", err
+		}
+		a.requeststore.Add(ctx, uid, payload)
+	}
+	metrics.ChallengesIssued.Inc()
+
+	return uid, nil
+}
+
+// validatePOW checks the solution and returns the quote request remembered with the challenge.
+func (a *App) validatePOW(ctx context.Context, clientResponse model.Message, addr string, id int) (req model.QuoteRequest, err error) {
+	ctx, span := tracer.Start(ctx, "verify solution")
+	defer func() { endSpan(span, err) }()
+
+	var claims token.Claims
+	if a.signer != nil {
+		var err error
+		if claims, err = a.signer.Verify(clientResponse.RequestID, addr); err != nil {
+			config.Logger.WithField("connection", id).Errorf("Failed to verify challenge '%s': %v", clientResponse.RequestID, err)
+			if errors.Is(err, token.ErrExpired) {
+				return a.rejectSolution(addr, metrics.ReasonExpired, ErrChallengeExpired)
+			}
+			return a.rejectSolution(addr, metrics.ReasonUnknownRequest, err)
+		}
+	}
+
+	// a hashcash stamp is checked once it's known what the challenge was issued with
+	stamped := clientResponse.MessageType == model.MessageTypeSolution && pow.IsStamp(clientResponse.MessageString)
+	solution, err := clientResponse.GetUint64()
+	if err != nil && !stamped {
 		config.Logger.WithField("connection", id).Error("Unable to parse solution. Closing connection")
-		return errors.New("unable to parse solution")
+		return a.rejectSolution(addr, metrics.ReasonBadParse, errors.New("unable to parse solution"))
 	}
 
-	if !a.challenge.IsValid(generatePOWChallenge(clientResponse.RequestID), solution) {
+	// the challenge is marked solved only once the solution is verified, so a wrong
+	// one doesn't burn it
+	payload := claims.Payload
+	if a.signer == nil {
+		if payload, err = a.requeststore.Get(ctx, clientResponse.RequestID); err != nil {
+			return a.rejectConsumed(clientResponse.RequestID, addr, err, id)
+		}
+	}
+	stored, err := decodeRequest(payload)
+	if err != nil {
+		config.Logger.WithField("connection", id).Errorf("Failed to decode request '%s': %v", clientResponse.RequestID, err)
+		return a.rejectSolution(addr, metrics.ReasonStoreError, err)
+	}
+	if a.signer != nil {
+		stored.Difficulty = claims.Difficulty
+	}
+
+	started := time.Now()
+	if stamped {
+		err := a.challenge.IsValidStamp(clientResponse.RequestID, clientResponse.MessageString, stored.Issued)
+		metrics.POWVerification.Observe(time.Since(started).Seconds())
+		if err != nil {
+			config.Logger.WithField("connection", id).Errorf("Hashcash stamp rejected: %v. Closing connection", err)
+			return a.rejectSolution(addr, stampReason(err), err)
+		}
+		return a.acceptSolution(ctx, clientResponse.RequestID, stored.QuoteRequest, addr, id)
+	}
+	valid := a.challenge.IsValid(generatePOWChallenge(clientResponse.RequestID), solution, stored.Issued)
+	metrics.POWVerification.Observe(time.Since(started).Seconds())
+	if !valid {
 		config.Logger.WithField("connection", id).Error("PoW verification failed. Closing connection")
-		return errors.New("pow verification failed")
+		return a.rejectSolution(addr, metrics.ReasonInvalidPOW, errors.New("pow verification failed"))
+	}
+	return a.acceptSolution(ctx, clientResponse.RequestID, stored.QuoteRequest, addr, id)
+}
+
+// acceptSolution marks a verified challenge solved, so that each challenge yields exactly
+// one quote, and returns its quote request. Of concurrent solutions only one is accepted.
+func (a *App) acceptSolution(ctx context.Context, requestID string, req model.QuoteRequest, addr string, id int) (model.QuoteRequest, error) {
+	var err error
+	if a.signer != nil {
+		err = a.replay.AddSolved(ctx, requestID)
+	} else {
+		_, err = a.requeststore.Consume(ctx, requestID)
+	}
+	if err != nil {
+		return a.rejectConsumed(requestID, addr, err, id)
 	}
+	metrics.SolutionsAccepted.Inc()
+	a.record(addr, reputation.EventSolved)
 
 	config.Logger.WithField("connection", id).Debug("PoW verification successful. Allowing connection")
 
-	return nil
+	return req, nil
+}
+
+// stampReason is the rejection reason of a hashcash stamp.
+func stampReason(err error) string {
+	switch {
+	case errors.Is(err, pow.ErrStampMalformed):
+		return metrics.ReasonBadParse
+	case errors.Is(err, pow.ErrStampDate):
+		return metrics.ReasonExpired
+	default:
+		return metrics.ReasonInvalidPOW
+	}
 }
 
-func (a *App) sendWOW(ctx context.Context, conn net.Conn, uid string, id int) error {
-	wow := a.storage.GetRandomWOW(ctx)
-	wowMessage := model.PrepareMessage(uid, model.MessageTypeWow, wow, 0)
+// rejectConsumed rejects a solution for a challenge that couldn't be marked solved.
+func (a *App) rejectConsumed(uid string, addr string, err error, id int) (model.QuoteRequest, error) {
+	switch {
+	case errors.Is(err, storage.ErrAlreadySolved):
+		config.Logger.WithField("connection", id).Errorf("This POW was already handled '%s'", uid)
+		return a.rejectSolution(addr, metrics.ReasonDoubleWork, errors.New("Double work"))
+	case errors.Is(err, storage.ErrExpired):
+		config.Logger.WithField("connection", id).Errorf("Challenge '%s' expired", uid)
+		return a.rejectSolution(addr, metrics.ReasonExpired, ErrChallengeExpired)
+	case errors.Is(err, storage.ErrNotFound):
+		config.Logger.WithField("connection", id).Errorf("Failed to find request '%s' in store: %v", uid, err)
+		return a.rejectSolution(addr, metrics.ReasonUnknownRequest, err)
+	default:
+		config.Logger.WithField("connection", id).Errorf("Failed to find request '%s' in store: %v", uid, err)
+		return a.rejectSolution(addr, metrics.ReasonStoreError, err)
+	}
+}
 
-	config.Logger.WithField("connection", id).Debugf("Prepared response: %s", string(wow))
+// startConnectionSpan starts the span of a connection once its first message is read,
+// as a child of the client span carried by the message. The span is backdated to
+// the accept time, with the read of the message as its first child.
+func startConnectionSpan(ctx context.Context, conn net.Conn, m model.Message, acceptedAt time.Time, id int) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(tracing.Extract(ctx, m.Trace), "wow.connection",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithTimestamp(acceptedAt),
+		trace.WithAttributes(
+			attribute.Int("wow.connection", id),
+			attribute.String("wow.message_type", m.MessageType),
+			attribute.String("net.peer.address", clientAddress(conn)),
+		))
 
-	if err := a.server.SendMessage(ctx, conn, wowMessage.AsJsonString()); err != nil {
-		config.Logger.WithField("connection", id).Errorf("Error while sending response: %v", err)
+	_, read := tracer.Start(ctx, "read request", trace.WithTimestamp(acceptedAt))
+	read.End()
+
+	return ctx, span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// rejectSolution counts the rejected solution and holds it against the reputation of addr.
+func (a *App) rejectSolution(addr string, reason string, err error) (model.QuoteRequest, error) {
+	metrics.SolutionsRejected.WithLabelValues(reason).Inc()
+	if event, ok := reasonEvents[reason]; ok {
+		a.record(addr, event)
+	}
+	return model.QuoteRequest{}, err
+}
+
+// reasonEvents are the rejections that make a source suspicious. Expired challenges
+// and store errors aren't the client's fault.
+var reasonEvents = map[string]reputation.Event{
+	metrics.ReasonBadParse:       reputation.EventMalformed,
+	metrics.ReasonInvalidPOW:     reputation.EventFailed,
+	metrics.ReasonDoubleWork:     reputation.EventFailed,
+	metrics.ReasonUnknownRequest: reputation.EventFailed,
+}
+
+func (a *App) record(addr string, event reputation.Event) {
+	if a.reputation != nil {
+		a.reputation.Record(addr, event)
+	}
+}
+
+func (a *App) sendWOW(ctx context.Context, conn net.Conn, uid string, req model.QuoteRequest, id int) error {
+	var quote model.Quote
+	var err error
+	kind := "random"
+	lookupCtx, lookup := tracer.Start(ctx, "quote lookup")
+	if req.Daily {
+		kind = "daily"
+		quote, err = a.storage.GetDailyQuote(lookupCtx, quoteDay(time.Now()))
+	} else {
+		quote, err = a.storage.GetRandomQuote(lookupCtx, req.QuoteFilter)
+	}
+	lookup.SetAttributes(attribute.String("wow.quote_kind", kind), attribute.String("wow.quote_id", quote.ID))
+	endSpan(lookup, err)
+	if errors.Is(err, storage.ErrNoMatchingQuote) {
+		config.Logger.WithField("connection", id).Warnf("No quote matches filter %+v", req.QuoteFilter)
+		errMessage := model.PrepareMessage(uid, model.MessageTypeError, err.Error(), 0)
+		if err := a.server.SendMessage(ctx, conn, errMessage.AsJsonString()); err != nil {
+			config.Logger.WithField("connection", id).Errorf("Error while sending response: %v", err)
+		}
 		return err
 	}
+	if err != nil {
+		config.Logger.WithField("connection", id).Errorf("Error while getting quote: %v", err)
+		return err
+	}
+
+	wowMessage := model.PrepareQuoteMessage(uid, quote)
+
+	config.Logger.WithField("connection", id).Debugf("Prepared response: quote %s", quote.ID)
 
-	if err := a.requeststore.Set(ctx, uid); err != nil {
-		config.Logger.WithField("connection", id).Errorf("Failed to set status for request '%s': %v", uid, err)
+	_, write := tracer.Start(ctx, "write quote")
+	err = a.server.SendMessage(ctx, conn, wowMessage.AsJsonString())
+	endSpan(write, err)
+	if err != nil {
+		config.Logger.WithField("connection", id).Errorf("Error while sending response: %v", err)
 		return err
 	}
+	metrics.QuotesDelivered.WithLabelValues(kind).Inc()
 
 	return nil
 }
 
+// quoteRequest reads the quote request from a request message.
+// The quote of the day is the same for everyone, so filters don't apply to it.
+func quoteRequest(m model.Message) model.QuoteRequest {
+	req := model.QuoteRequest{ClientID: m.ClientID}
+	switch {
+	case m.MessageType == model.MessageTypeDaily:
+		req.Daily = true
+	case m.Filter != nil:
+		req.QuoteFilter = *m.Filter
+	}
+	return req
+}
+
+// storedRequest is the payload remembered with a challenge: the requested quote and
+// what the challenge was issued with, except the difficulty of signed ones.
+type storedRequest struct {
+	model.QuoteRequest
+	pow.Issued
+}
+
+// encodeRequest keeps challenges for a plain random quote as small as before.
+func encodeRequest(req model.QuoteRequest, issued pow.Issued) (string, error) {
+	if req.IsEmpty() && req.ClientID == "" && issued == (pow.Issued{}) {
+		return "", nil
+	}
+	payload, err := json.Marshal(storedRequest{QuoteRequest: req, Issued: issued})
+	return string(payload), err
+}
+
+func decodeRequest(payload string) (storedRequest, error) {
+	var stored storedRequest
+	if payload == "" {
+		return stored, nil
+	}
+	err := json.Unmarshal([]byte(payload), &stored)
+	return stored, err
+}
+
+// quoteDay is the calendar day of now in the configured timezone.
+func quoteDay(now time.Time) string {
+	loc := config.Config.DailyLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	return now.In(loc).Format(time.DateOnly)
+}
+
+// clientIdentity prefers the client ID remembered with the challenge over the client
+// address. Only signed challenges remember it, see sendChallenge.
+func clientIdentity(conn net.Conn, req model.QuoteRequest) string {
+	if req.ClientID != "" {
+		return req.ClientID
+	}
+	return clientAddress(conn)
+}
+
+func clientAddress(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+func stepTimeout() time.Duration {
+	return time.Millisecond * time.Duration(config.Config.StepTimeout)
+}
+
 func generatePOWChallenge(cnt string) string {
 	return fmt.Sprintf("%s %s", config.Config.ProofString, cnt)
 }
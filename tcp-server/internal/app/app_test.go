@@ -3,16 +3,25 @@ package app
 import (
 	"context"
 	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/pullya/wow_tcp_server/shared/pow"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/app/mocks"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/metrics"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/reputation"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/server"
 	serverMocks "github.com/pullya/wow_tcp_server/tcp-server/internal/server/mocks"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/storage"
 	storageMocks "github.com/pullya/wow_tcp_server/tcp-server/internal/storage/mocks"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/token"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -84,6 +93,7 @@ func TestApp_sendChallenge(t *testing.T) {
 				challengeMock := &mocks.Challenger{}
 
 				challengeMock.On("Difficulty").Return(10)
+				challengeMock.On("Negotiate", mock.Anything, 10).Return(pow.Keccak{}, 10, nil)
 				serverMock.On("SendMessage", mock.Anything, tConn, mock.Anything).Return(errors.New("error"))
 
 				return fields{
@@ -109,8 +119,9 @@ func TestApp_sendChallenge(t *testing.T) {
 				challengeMock := &mocks.Challenger{}
 
 				challengeMock.On("Difficulty").Return(10)
+				challengeMock.On("Negotiate", mock.Anything, 10).Return(pow.Keccak{}, 10, nil)
 				serverMock.On("SendMessage", mock.Anything, tConn, mock.Anything).Return(nil)
-				requeststoreMock.On("Add", mock.Anything, mock.Anything).Return()
+				requeststoreMock.On("Add", mock.Anything, mock.Anything, `{"difficulty":10}`).Return()
 
 				return fields{
 					server:       serverMock,
@@ -126,6 +137,126 @@ func TestApp_sendChallenge(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Memory-hard challenge carries its parameters",
+			fields: func() fields {
+				serverMock := &serverMocks.ServerProvider{}
+				storageMock := &storageMocks.Storageer{}
+				requeststoreMock := &storageMocks.Requester{}
+				challengeMock := &mocks.Challenger{}
+				algorithm := pow.Argon2id{Time: 1, Memory: 1024, Threads: 1}
+
+				challengeMock.On("Difficulty").Return(20)
+				challengeMock.On("Negotiate", mock.Anything, 20).Return(algorithm, 4, nil)
+				serverMock.On("SendMessage", mock.Anything, tConn, mock.MatchedBy(func(b []byte) bool {
+					msg, err := model.ParseServerMessage(string(b))
+					return err == nil && msg.Algorithm == model.AlgorithmArgon2id && reflect.DeepEqual(msg.Params, algorithm.Params())
+				})).Return(nil)
+				requeststoreMock.On("Add", mock.Anything, mock.Anything, `{"difficulty":4,"algorithm":"argon2id"}`).Return()
+
+				return fields{
+					server:       serverMock,
+					storage:      storageMock,
+					requeststore: requeststoreMock,
+					challenge:    challengeMock,
+				}
+			},
+			args: args{
+				ctx,
+				tConn,
+				21,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Epoch is remembered with the challenge",
+			fields: func() fields {
+				serverMock := &serverMocks.ServerProvider{}
+				storageMock := &storageMocks.Storageer{}
+				requeststoreMock := &storageMocks.Requester{}
+				challengeMock := &mocks.Challenger{}
+
+				challengeMock.On("Difficulty").Return(16)
+				challengeMock.On("Negotiate", mock.Anything, 16).Return(epochAlgorithm{}, 16, nil)
+				serverMock.On("SendMessage", mock.Anything, tConn, mock.MatchedBy(func(b []byte) bool {
+					msg, err := model.ParseServerMessage(string(b))
+					return err == nil && msg.Params != nil && msg.Params.Epoch == 7
+				})).Return(nil)
+				requeststoreMock.On("Add", mock.Anything, mock.Anything, `{"difficulty":16,"epoch":7}`).Return()
+
+				return fields{
+					server:       serverMock,
+					storage:      storageMock,
+					requeststore: requeststoreMock,
+					challenge:    challengeMock,
+				}
+			},
+			args: args{
+				ctx,
+				tConn,
+				21,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Hashcash challenge is the resource of the stamp",
+			fields: func() fields {
+				serverMock := &serverMocks.ServerProvider{}
+				storageMock := &storageMocks.Storageer{}
+				requeststoreMock := &storageMocks.Requester{}
+				challengeMock := &mocks.Challenger{}
+
+				challengeMock.On("Difficulty").Return(10)
+				challengeMock.On("Negotiate", mock.Anything, 10).Return(pow.Hashcash{Hasher: model.HashSHA256}, 10, nil)
+				serverMock.On("SendMessage", mock.Anything, tConn, mock.MatchedBy(func(b []byte) bool {
+					msg, err := model.ParseServerMessage(string(b))
+					return err == nil && msg.MessageString == msg.RequestID && msg.Params != nil && msg.Params.Hash == model.HashSHA256
+				})).Return(nil)
+				requeststoreMock.On("Add", mock.Anything, mock.Anything, `{"difficulty":10,"algorithm":"hashcash"}`).Return()
+
+				return fields{
+					server:       serverMock,
+					storage:      storageMock,
+					requeststore: requeststoreMock,
+					challenge:    challengeMock,
+				}
+			},
+			args: args{
+				ctx,
+				tConn,
+				21,
+			},
+			wantErr: false,
+		},
+		{
+			name: "No common algorithm",
+			fields: func() fields {
+				serverMock := &serverMocks.ServerProvider{}
+				storageMock := &storageMocks.Storageer{}
+				requeststoreMock := &storageMocks.Requester{}
+				challengeMock := &mocks.Challenger{}
+
+				challengeMock.On("Difficulty").Return(10)
+				challengeMock.On("Negotiate", mock.Anything, 10).Return(nil, 0, pow.ErrNoCommonAlgorithm)
+				serverMock.On("SendMessage", mock.Anything, tConn, mock.MatchedBy(func(b []byte) bool {
+					msg, err := model.ParseServerMessage(string(b))
+					return err == nil && msg.MessageType == model.MessageTypeError
+				})).Return(nil)
+
+				return fields{
+					server:       serverMock,
+					storage:      storageMock,
+					requeststore: requeststoreMock,
+					challenge:    challengeMock,
+				}
+			},
+			args: args{
+				ctx,
+				tConn,
+				21,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -135,13 +266,44 @@ func TestApp_sendChallenge(t *testing.T) {
 				requeststore: tt.fields().requeststore,
 				challenge:    tt.fields().challenge,
 			}
-			if err := a.sendChallenge(tt.args.ctx, tt.args.conn, tt.args.id); (err != nil) != tt.wantErr {
+			if _, err := a.sendChallenge(tt.args.ctx, tt.args.conn, model.QuoteRequest{}, nil, tt.args.id); (err != nil) != tt.wantErr {
 				t.Errorf("App.sendChallenge() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
+// Not parallel, since the gauge is shared by every test that issues a challenge.
+func TestApp_sendChallenge_difficultyMetric(t *testing.T) {
+	config.InitLogger()
+
+	serverMock := &serverMocks.ServerProvider{}
+	requeststoreMock := &storageMocks.Requester{}
+	challengeMock := &mocks.Challenger{}
+
+	challengeMock.On("Difficulty").Return(20)
+	challengeMock.On("Negotiate", mock.Anything, 20).Return(pow.Argon2id{Time: 1, Memory: 1024, Threads: 1}, 4, nil)
+	serverMock.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	requeststoreMock.On("Add", mock.Anything, mock.Anything, mock.Anything).Return()
+
+	a := &App{server: serverMock, requeststore: requeststoreMock, challenge: challengeMock}
+	if _, err := a.sendChallenge(context.Background(), nil, model.QuoteRequest{}, nil, 1); err != nil {
+		t.Fatalf("App.sendChallenge() error = %v", err)
+	}
+	if got := testutil.ToFloat64(metrics.Difficulty); got != 4 {
+		t.Errorf("wow_difficulty = %v, want the negotiated difficulty 4", got)
+	}
+}
+
+// epochAlgorithm announces epoch 7 with every challenge.
+type epochAlgorithm struct {
+	pow.Keccak
+}
+
+func (epochAlgorithm) Params() *model.PowParams {
+	return &model.PowParams{Epoch: 7}
+}
+
 func TestApp_validatePOW(t *testing.T) {
 	t.Parallel()
 
@@ -174,7 +336,8 @@ func TestApp_validatePOW(t *testing.T) {
 				requeststoreMock := &storageMocks.Requester{}
 				challengeMock := &mocks.Challenger{}
 
-				requeststoreMock.On("Get", mock.Anything, uid).Return(false, errors.New("error"))
+				challengeMock.On("IsValid", mock.Anything, uint64(2450), mock.Anything).Return(true)
+				requeststoreMock.On("Get", mock.Anything, uid).Return("", storage.ErrNotFound)
 
 				return fields{
 					server:       serverMock,
@@ -185,7 +348,7 @@ func TestApp_validatePOW(t *testing.T) {
 			},
 			args: args{
 				ctx,
-				model.Message{RequestID: uid, MessageType: model.MessageTypeRequest, MessageString: "answer", Difficulty: 21},
+				model.Message{RequestID: uid, MessageType: model.MessageTypeSolution, MessageString: "2450", Difficulty: 21},
 				21,
 			},
 			wantErr: true,
@@ -198,7 +361,8 @@ func TestApp_validatePOW(t *testing.T) {
 				requeststoreMock := &storageMocks.Requester{}
 				challengeMock := &mocks.Challenger{}
 
-				requeststoreMock.On("Get", mock.Anything, uid).Return(true, nil)
+				challengeMock.On("IsValid", mock.Anything, uint64(2450), mock.Anything).Return(true)
+				requeststoreMock.On("Get", mock.Anything, uid).Return("", storage.ErrAlreadySolved)
 
 				return fields{
 					server:       serverMock,
@@ -209,7 +373,7 @@ func TestApp_validatePOW(t *testing.T) {
 			},
 			args: args{
 				ctx,
-				model.Message{RequestID: uid, MessageType: model.MessageTypeRequest, MessageString: "answer", Difficulty: 21},
+				model.Message{RequestID: uid, MessageType: model.MessageTypeSolution, MessageString: "2450", Difficulty: 21},
 				21,
 			},
 			wantErr: true,
@@ -222,8 +386,6 @@ func TestApp_validatePOW(t *testing.T) {
 				requeststoreMock := &storageMocks.Requester{}
 				challengeMock := &mocks.Challenger{}
 
-				requeststoreMock.On("Get", mock.Anything, uid).Return(false, nil)
-
 				return fields{
 					server:       serverMock,
 					storage:      storageMock,
@@ -246,8 +408,8 @@ func TestApp_validatePOW(t *testing.T) {
 				requeststoreMock := &storageMocks.Requester{}
 				challengeMock := &mocks.Challenger{}
 
-				requeststoreMock.On("Get", mock.Anything, uid).Return(false, nil)
-				challengeMock.On("IsValid", mock.Anything, uint64(2450)).Return(false)
+				challengeMock.On("IsValid", mock.Anything, uint64(2450), pow.Issued{Difficulty: 21}).Return(false)
+				requeststoreMock.On("Get", mock.Anything, uid).Return(`{"difficulty":21}`, nil)
 
 				return fields{
 					server:       serverMock,
@@ -271,8 +433,9 @@ func TestApp_validatePOW(t *testing.T) {
 				requeststoreMock := &storageMocks.Requester{}
 				challengeMock := &mocks.Challenger{}
 
-				requeststoreMock.On("Get", mock.Anything, uid).Return(false, nil)
-				challengeMock.On("IsValid", mock.Anything, uint64(2450)).Return(true)
+				challengeMock.On("IsValid", mock.Anything, uint64(2450), mock.Anything).Return(true)
+				requeststoreMock.On("Get", mock.Anything, uid).Return("", nil)
+				requeststoreMock.On("Consume", mock.Anything, uid).Return("", nil)
 
 				return fields{
 					server:       serverMock,
@@ -288,6 +451,84 @@ func TestApp_validatePOW(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Success at the issued epoch",
+			fields: func() fields {
+				serverMock := &serverMocks.ServerProvider{}
+				storageMock := &storageMocks.Storageer{}
+				requeststoreMock := &storageMocks.Requester{}
+				challengeMock := &mocks.Challenger{}
+
+				challengeMock.On("IsValid", mock.Anything, uint64(2450), pow.Issued{Difficulty: 16, Epoch: 7}).Return(true)
+				requeststoreMock.On("Get", mock.Anything, uid).Return(`{"difficulty":16,"epoch":7}`, nil)
+				requeststoreMock.On("Consume", mock.Anything, uid).Return(`{"difficulty":16,"epoch":7}`, nil)
+
+				return fields{
+					server:       serverMock,
+					storage:      storageMock,
+					requeststore: requeststoreMock,
+					challenge:    challengeMock,
+				}
+			},
+			args: args{
+				ctx,
+				model.Message{RequestID: uid, MessageType: model.MessageTypeSolution, MessageString: "2450", Difficulty: 16},
+				21,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Success hashcash stamp",
+			fields: func() fields {
+				serverMock := &serverMocks.ServerProvider{}
+				storageMock := &storageMocks.Storageer{}
+				requeststoreMock := &storageMocks.Requester{}
+				challengeMock := &mocks.Challenger{}
+
+				issued := pow.Issued{Difficulty: 16, Algorithm: model.AlgorithmHashcash}
+				challengeMock.On("IsValidStamp", uid, "1:16:260101120000:"+uid+"::rand:1234", issued).Return(nil)
+				requeststoreMock.On("Get", mock.Anything, uid).Return(`{"difficulty":16,"algorithm":"hashcash"}`, nil)
+				requeststoreMock.On("Consume", mock.Anything, uid).Return(`{"difficulty":16,"algorithm":"hashcash"}`, nil)
+
+				return fields{
+					server:       serverMock,
+					storage:      storageMock,
+					requeststore: requeststoreMock,
+					challenge:    challengeMock,
+				}
+			},
+			args: args{
+				ctx,
+				model.Message{RequestID: uid, MessageType: model.MessageTypeSolution, MessageString: "1:16:260101120000:" + uid + "::rand:1234", Difficulty: 16},
+				21,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Error hashcash stamp out of date",
+			fields: func() fields {
+				serverMock := &serverMocks.ServerProvider{}
+				storageMock := &storageMocks.Storageer{}
+				requeststoreMock := &storageMocks.Requester{}
+				challengeMock := &mocks.Challenger{}
+
+				challengeMock.On("IsValidStamp", uid, mock.Anything, mock.Anything).Return(pow.ErrStampDate)
+				requeststoreMock.On("Get", mock.Anything, uid).Return(`{"difficulty":16,"algorithm":"hashcash"}`, nil)
+
+				return fields{
+					server:       serverMock,
+					storage:      storageMock,
+					requeststore: requeststoreMock,
+					challenge:    challengeMock,
+				}
+			},
+			args: args{
+				ctx,
+				model.Message{RequestID: uid, MessageType: model.MessageTypeSolution, MessageString: "1:16:250101:" + uid + "::rand:1234", Difficulty: 16},
+				21,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -297,13 +538,91 @@ func TestApp_validatePOW(t *testing.T) {
 				requeststore: tt.fields().requeststore,
 				challenge:    tt.fields().challenge,
 			}
-			if err := a.validatePOW(tt.args.ctx, tt.args.clientResponse, tt.args.id); (err != nil) != tt.wantErr {
+			if _, err := a.validatePOW(tt.args.ctx, tt.args.clientResponse, "", tt.args.id); (err != nil) != tt.wantErr {
 				t.Errorf("App.validatePOW() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
+// not parallel: the metrics are global
+func TestApp_validatePOW_metrics(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	uid := storage.GenUID()
+
+	tests := []struct {
+		name       string
+		solution   string
+		valid      bool
+		consumeErr error
+		wantReason string
+	}{
+		{
+			name:       "Bad parse",
+			solution:   "answer",
+			wantReason: metrics.ReasonBadParse,
+		},
+		{
+			name:       "Invalid PoW",
+			solution:   "2450",
+			valid:      false,
+			wantReason: metrics.ReasonInvalidPOW,
+		},
+		{
+			name:       "Double work",
+			solution:   "2450",
+			valid:      true,
+			consumeErr: storage.ErrAlreadySolved,
+			wantReason: metrics.ReasonDoubleWork,
+		},
+		{
+			name:       "Unknown request",
+			solution:   "2450",
+			valid:      true,
+			consumeErr: storage.ErrNotFound,
+			wantReason: metrics.ReasonUnknownRequest,
+		},
+		{
+			name:     "Accepted",
+			solution: "2450",
+			valid:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challengeMock := &mocks.Challenger{}
+			challengeMock.On("IsValid", mock.Anything, uint64(2450), mock.Anything).Return(tt.valid)
+			requeststoreMock := &storageMocks.Requester{}
+			requeststoreMock.On("Get", mock.Anything, uid).Return("", nil)
+			requeststoreMock.On("Consume", mock.Anything, uid).Return("", tt.consumeErr)
+
+			a := &App{requeststore: requeststoreMock, challenge: challengeMock}
+
+			accepted := testutil.ToFloat64(metrics.SolutionsAccepted)
+			var rejected float64
+			if tt.wantReason != "" {
+				rejected = testutil.ToFloat64(metrics.SolutionsRejected.WithLabelValues(tt.wantReason))
+			}
+
+			_, _ = a.validatePOW(ctx, model.Message{RequestID: uid, MessageType: model.MessageTypeSolution, MessageString: tt.solution}, "", 1)
+
+			if tt.wantReason == "" {
+				if got := testutil.ToFloat64(metrics.SolutionsAccepted) - accepted; got != 1 {
+					t.Errorf("solutions accepted increased by %v, want 1", got)
+				}
+				return
+			}
+			if got := testutil.ToFloat64(metrics.SolutionsRejected.WithLabelValues(tt.wantReason)) - rejected; got != 1 {
+				t.Errorf("solutions rejected for %s increased by %v, want 1", tt.wantReason, got)
+			}
+			if got := testutil.ToFloat64(metrics.SolutionsAccepted) - accepted; got != 0 {
+				t.Errorf("solutions accepted increased by %v, want 0", got)
+			}
+		})
+	}
+}
+
 func TestApp_sendWOW(t *testing.T) {
 	t.Parallel()
 
@@ -311,6 +630,7 @@ func TestApp_sendWOW(t *testing.T) {
 	config.InitLogger()
 	ctx := context.Background()
 	uid := storage.GenUID()
+	quote := model.Quote{ID: "q1", Text: "Random Word of Wisdom", Author: "Anonymous", Language: "en", Tags: []string{"test"}}
 
 	type fields struct {
 		server       server.ServerProvider
@@ -338,7 +658,7 @@ func TestApp_sendWOW(t *testing.T) {
 				requeststoreMock := &storageMocks.Requester{}
 				challengeMock := &mocks.Challenger{}
 
-				storageMock.On("GetRandomWOW", mock.Anything).Return("Random Word of Wisdom")
+				storageMock.On("GetRandomQuote", mock.Anything, model.QuoteFilter{}).Return(quote, nil)
 				serverMock.On("SendMessage", mock.Anything, tConn, mock.Anything).Return(errors.New("error"))
 
 				return fields{
@@ -356,33 +676,6 @@ func TestApp_sendWOW(t *testing.T) {
 			},
 			wantErr: true,
 		},
-		{
-			name: "Error sending message",
-			fields: func() fields {
-				serverMock := &serverMocks.ServerProvider{}
-				storageMock := &storageMocks.Storageer{}
-				requeststoreMock := &storageMocks.Requester{}
-				challengeMock := &mocks.Challenger{}
-
-				storageMock.On("GetRandomWOW", mock.Anything).Return("Random Word of Wisdom")
-				serverMock.On("SendMessage", mock.Anything, tConn, mock.Anything).Return(nil)
-				requeststoreMock.On("Set", mock.Anything, uid).Return(errors.New("error"))
-
-				return fields{
-					server:       serverMock,
-					storage:      storageMock,
-					requeststore: requeststoreMock,
-					challenge:    challengeMock,
-				}
-			},
-			args: args{
-				ctx,
-				tConn,
-				uid,
-				21,
-			},
-			wantErr: true,
-		},
 		{
 			name: "Success",
 			fields: func() fields {
@@ -391,9 +684,11 @@ func TestApp_sendWOW(t *testing.T) {
 				requeststoreMock := &storageMocks.Requester{}
 				challengeMock := &mocks.Challenger{}
 
-				storageMock.On("GetRandomWOW", mock.Anything).Return("Random Word of Wisdom")
-				serverMock.On("SendMessage", mock.Anything, tConn, mock.Anything).Return(nil)
-				requeststoreMock.On("Set", mock.Anything, uid).Return(nil)
+				storageMock.On("GetRandomQuote", mock.Anything, model.QuoteFilter{}).Return(quote, nil)
+				serverMock.On("SendMessage", mock.Anything, tConn, mock.MatchedBy(func(b []byte) bool {
+					msg, err := model.ParseServerMessage(string(b))
+					return err == nil && msg.MessageString == quote.Text && reflect.DeepEqual(msg.Quote, &quote)
+				})).Return(nil)
 
 				return fields{
 					server:       serverMock,
@@ -419,9 +714,652 @@ func TestApp_sendWOW(t *testing.T) {
 				requeststore: tt.fields().requeststore,
 				challenge:    tt.fields().challenge,
 			}
-			if err := a.sendWOW(tt.args.ctx, tt.args.conn, tt.args.uid, tt.args.id); (err != nil) != tt.wantErr {
+			if err := a.sendWOW(tt.args.ctx, tt.args.conn, tt.args.uid, model.QuoteRequest{}, tt.args.id); (err != nil) != tt.wantErr {
 				t.Errorf("App.sendWOW() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestApp_handleSession(t *testing.T) {
+	t.Parallel()
+
+	config.InitLogger()
+	ctx := context.Background()
+
+	requestMessage := string(model.PrepareMessage("", model.MessageTypeRequest, "", 0).AsJsonString())
+
+	tests := []struct {
+		name     string
+		first    string
+		solution func(uid string) string
+		wantWOW  bool
+	}{
+		{
+			name:  "Success",
+			first: requestMessage,
+			solution: func(uid string) string {
+				return string(model.PrepareMessage(uid, model.MessageTypeSolution, "2450", 10).AsJsonString())
+			},
+			wantWOW: true,
+		},
+		{
+			name:  "Solution for another challenge",
+			first: requestMessage,
+			solution: func(string) string {
+				return string(model.PrepareMessage(storage.GenUID(), model.MessageTypeSolution, "2450", 10).AsJsonString())
+			},
+			wantWOW: false,
+		},
+		{
+			name:  "Session doesn't start with request",
+			first: string(model.PrepareMessage("", model.MessageTypeSolution, "2450", 10).AsJsonString()),
+			solution: func(string) string {
+				return ""
+			},
+			wantWOW: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn, peer := net.Pipe()
+			defer peer.Close()
+
+			var issued string
+			var wowSent bool
+			serverMock := &serverMocks.ServerProvider{}
+			storageMock := &storageMocks.Storageer{}
+			requeststoreMock := &storageMocks.Requester{}
+			challengeMock := &mocks.Challenger{}
+
+			challengeMock.On("Difficulty").Return(10)
+			challengeMock.On("Negotiate", mock.Anything, 10).Return(pow.Keccak{}, 10, nil)
+			challengeMock.On("IsValid", mock.Anything, uint64(2450), mock.Anything).Return(true)
+			serverMock.On("ReceiveMessage", mock.Anything, conn).Return(tt.first, nil).Once()
+			serverMock.On("ReceiveMessage", mock.Anything, conn).Return(func(context.Context, net.Conn) (string, error) {
+				return tt.solution(issued), nil
+			}).Once()
+			serverMock.On("SendMessage", mock.Anything, conn, mock.Anything).Run(func(args mock.Arguments) {
+				msg, err := model.ParseServerMessage(string(args.Get(2).([]byte)))
+				if err != nil {
+					return
+				}
+				switch msg.MessageType {
+				case model.MessageTypeChallenge:
+					issued = msg.RequestID
+				case model.MessageTypeWow:
+					wowSent = true
+				}
+			}).Return(nil)
+			requeststoreMock.On("Add", mock.Anything, mock.Anything, `{"difficulty":10}`).Return()
+			requeststoreMock.On("Get", mock.Anything, mock.Anything).Return("", nil)
+			requeststoreMock.On("Consume", mock.Anything, mock.Anything).Return("", nil)
+			storageMock.On("GetRandomQuote", mock.Anything, model.QuoteFilter{}).Return(model.Quote{ID: "q1", Text: "Random Word of Wisdom"}, nil)
+
+			a := &App{
+				server:       serverMock,
+				storage:      storageMock,
+				requeststore: requeststoreMock,
+				challenge:    challengeMock,
+			}
+			a.handleSession(ctx, conn, 21)
+
+			if wowSent != tt.wantWOW {
+				t.Errorf("App.handleSession() sent wow = %v, want %v", wowSent, tt.wantWOW)
+			}
+		})
+	}
+}
+
+func TestApp_validatePOW_signed(t *testing.T) {
+	t.Parallel()
+
+	config.InitLogger()
+	ctx := context.Background()
+
+	// challenges issued by one replica are verified by another one sharing the secret
+	issued, err := token.New([]byte("secret"), time.Minute).Issue("10.0.0.1", 10, "")
+	if err != nil {
+		t.Fatalf("Signer.Issue() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		requestID string
+		addr      string
+		addSolved error
+		wantErr   bool
+	}{
+		{
+			name:      "Success",
+			requestID: issued,
+			addr:      "10.0.0.1",
+			addSolved: nil,
+			wantErr:   false,
+		},
+		{
+			name:      "Error solution from another address",
+			requestID: issued,
+			addr:      "10.0.0.2",
+			addSolved: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "Error forged challenge",
+			requestID: storage.GenUID(),
+			addr:      "10.0.0.1",
+			addSolved: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "Error challenge already solved",
+			requestID: issued,
+			addr:      "10.0.0.1",
+			addSolved: storage.ErrAlreadySolved,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replayMock := &storageMocks.ReplayDetector{}
+			challengeMock := &mocks.Challenger{}
+
+			challengeMock.On("IsValid", mock.Anything, uint64(2450), pow.Issued{Difficulty: 10}).Return(true)
+			replayMock.On("AddSolved", mock.Anything, tt.requestID).Return(tt.addSolved)
+
+			a := &App{
+				server:       &serverMocks.ServerProvider{},
+				storage:      &storageMocks.Storageer{},
+				requeststore: &storageMocks.Requester{},
+				challenge:    challengeMock,
+				signer:       token.New([]byte("secret"), time.Minute),
+				replay:       replayMock,
+			}
+			clientResponse := model.Message{RequestID: tt.requestID, MessageType: model.MessageTypeSolution, MessageString: "2450", Difficulty: 10}
+			if _, err := a.validatePOW(ctx, clientResponse, tt.addr, 21); (err != nil) != tt.wantErr {
+				t.Errorf("App.validatePOW() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApp_validatePOW_expired(t *testing.T) {
+	t.Parallel()
+
+	config.InitLogger()
+	uid := storage.GenUID()
+
+	requeststoreMock := &storageMocks.Requester{}
+	requeststoreMock.On("Get", mock.Anything, uid).Return("", storage.ErrExpired)
+	challengeMock := &mocks.Challenger{}
+	challengeMock.On("IsValid", mock.Anything, uint64(2450), mock.Anything).Return(true)
+
+	a := &App{
+		server:       &serverMocks.ServerProvider{},
+		storage:      &storageMocks.Storageer{},
+		requeststore: requeststoreMock,
+		challenge:    challengeMock,
+	}
+	clientResponse := model.Message{RequestID: uid, MessageType: model.MessageTypeSolution, MessageString: "2450", Difficulty: 10}
+	if _, err := a.validatePOW(context.Background(), clientResponse, "", 21); !errors.Is(err, ErrChallengeExpired) {
+		t.Errorf("App.validatePOW() error = %v, want %v", err, ErrChallengeExpired)
+	}
+}
+
+func TestApp_validatePOW_wrongSolutionKeepsChallenge(t *testing.T) {
+	t.Parallel()
+
+	config.InitLogger()
+	uid := storage.GenUID()
+	ctx := context.Background()
+
+	requeststore := storage.NewRequestStore(1, func(string) uint32 { return 0 }, time.Minute, time.Minute)
+	requeststore.Add(ctx, uid, `{"difficulty":10}`)
+	challengeMock := &mocks.Challenger{}
+	challengeMock.On("IsValid", mock.Anything, uint64(1), mock.Anything).Return(false)
+	challengeMock.On("IsValid", mock.Anything, uint64(2450), mock.Anything).Return(true)
+
+	a := &App{requeststore: requeststore, challenge: challengeMock}
+
+	for _, solution := range []string{"1", "answer"} {
+		clientResponse := model.Message{RequestID: uid, MessageType: model.MessageTypeSolution, MessageString: solution}
+		if _, err := a.validatePOW(ctx, clientResponse, "", 21); err == nil {
+			t.Fatalf("App.validatePOW(%s) accepted a wrong solution", solution)
+		}
+	}
+	clientResponse := model.Message{RequestID: uid, MessageType: model.MessageTypeSolution, MessageString: "2450"}
+	if _, err := a.validatePOW(ctx, clientResponse, "", 21); err != nil {
+		t.Errorf("App.validatePOW() after wrong solutions error = %v", err)
+	}
+	if _, err := a.validatePOW(ctx, clientResponse, "", 21); err == nil {
+		t.Error("App.validatePOW() accepted the solution twice")
+	}
+}
+
+func TestApp_validatePOW_duplicateSolutions(t *testing.T) {
+	t.Parallel()
+
+	config.InitLogger()
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		signer *token.Signer
+		replay storage.ReplayDetector
+	}{
+		{
+			name:   "Stored challenges",
+			signer: nil,
+			replay: nil,
+		},
+		{
+			name:   "Signed challenges",
+			signer: token.New([]byte("secret"), time.Minute),
+			replay: nil,
+		},
+		{
+			name:   "Signed challenges with bloom replay detector",
+			signer: token.New([]byte("secret"), time.Minute),
+			replay: storage.NewBloomReplayDetector(1000, 0.001, time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requeststore := storage.NewRequestStore(8, func(in string) uint32 {
+				return storage.HashShard([]byte(in)) % 8
+			}, time.Minute, time.Minute)
+			challengeMock := &mocks.Challenger{}
+			challengeMock.On("IsValid", mock.Anything, uint64(2450), mock.Anything).Return(true)
+
+			a := &App{
+				server:       &serverMocks.ServerProvider{},
+				storage:      &storageMocks.Storageer{},
+				requeststore: requeststore,
+				challenge:    challengeMock,
+				signer:       tt.signer,
+				replay:       tt.replay,
+			}
+			if a.replay == nil {
+				a.replay = requeststore
+			}
+
+			uid := storage.GenUID()
+			if tt.signer != nil {
+				signed, err := tt.signer.Issue("10.0.0.1", 10, "")
+				if err != nil {
+					t.Fatalf("Signer.Issue() error = %v", err)
+				}
+				uid = signed
+			} else {
+				requeststore.Add(ctx, uid, "")
+			}
+			clientResponse := model.Message{RequestID: uid, MessageType: model.MessageTypeSolution, MessageString: "2450", Difficulty: 10}
+
+			const attempts = 64
+			var accepted atomic.Int32
+			var wg sync.WaitGroup
+			start := make(chan struct{})
+			for i := 0; i < attempts; i++ {
+				wg.Add(1)
+				go func(id int) {
+					defer wg.Done()
+					<-start
+					if _, err := a.validatePOW(ctx, clientResponse, "10.0.0.1", id); err == nil {
+						accepted.Add(1)
+					}
+				}(i)
+			}
+			close(start)
+			wg.Wait()
+
+			if got := accepted.Load(); got != 1 {
+				t.Errorf("App.validatePOW() accepted %d duplicate solutions, want 1", got)
+			}
+		})
+	}
+}
+
+func TestApp_challengeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	config.InitLogger()
+	ctx := context.Background()
+	filter := model.QuoteRequest{QuoteFilter: model.QuoteFilter{Tags: []string{"life"}, Author: "Seneca", Language: "en"}}
+	daily := model.QuoteRequest{Daily: true}
+	client := model.QuoteRequest{ClientID: "dashboard-1"}
+
+	tests := []struct {
+		name   string
+		signer *token.Signer
+		req    model.QuoteRequest
+		want   *model.QuoteRequest // the request itself if nil
+	}{
+		{
+			name:   "Stored challenge with filter",
+			signer: nil,
+			req:    filter,
+		},
+		{
+			name:   "Signed challenge with filter",
+			signer: token.New([]byte("secret"), time.Minute),
+			req:    filter,
+		},
+		{
+			name:   "Stored quote of the day",
+			signer: nil,
+			req:    daily,
+		},
+		{
+			name:   "Signed quote of the day",
+			signer: token.New([]byte("secret"), time.Minute),
+			req:    daily,
+		},
+		{
+			name:   "Stored challenge drops the client ID",
+			signer: nil,
+			req:    client,
+			want:   &model.QuoteRequest{},
+		},
+		{
+			name:   "Signed challenge keeps the client ID",
+			signer: token.New([]byte("secret"), time.Minute),
+			req:    client,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn, peer := net.Pipe()
+			defer conn.Close()
+			defer peer.Close()
+
+			requeststore := storage.NewRequestStore(1, func(string) uint32 { return 0 }, time.Minute, time.Minute)
+			serverMock := &serverMocks.ServerProvider{}
+			challengeMock := &mocks.Challenger{}
+			serverMock.On("SendMessage", mock.Anything, conn, mock.Anything).Return(nil)
+			challengeMock.On("Difficulty").Return(10)
+			challengeMock.On("Negotiate", mock.Anything, 10).Return(pow.Keccak{}, 10, nil)
+			challengeMock.On("IsValid", mock.Anything, uint64(2450), mock.Anything).Return(true)
+
+			a := &App{
+				server:       serverMock,
+				storage:      &storageMocks.Storageer{},
+				requeststore: requeststore,
+				challenge:    challengeMock,
+				signer:       tt.signer,
+				replay:       requeststore,
+			}
+
+			uid, err := a.sendChallenge(ctx, conn, tt.req, nil, 21)
+			if err != nil {
+				t.Fatalf("App.sendChallenge() error = %v", err)
+			}
+			clientResponse := model.Message{RequestID: uid, MessageType: model.MessageTypeSolution, MessageString: "2450", Difficulty: 10}
+
+			got, err := a.validatePOW(ctx, clientResponse, clientAddress(conn), 21)
+			if err != nil {
+				t.Fatalf("App.validatePOW() error = %v", err)
+			}
+			want := tt.req
+			if tt.want != nil {
+				want = *tt.want
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("App.validatePOW() request = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestApp_sendWOW_noMatchingQuote(t *testing.T) {
+	t.Parallel()
+
+	config.InitLogger()
+	tConn := *new(net.Conn)
+	filter := model.QuoteFilter{Author: "Nobody"}
+
+	serverMock := &serverMocks.ServerProvider{}
+	storageMock := &storageMocks.Storageer{}
+	storageMock.On("GetRandomQuote", mock.Anything, filter).Return(model.Quote{}, storage.ErrNoMatchingQuote)
+	serverMock.On("SendMessage", mock.Anything, tConn, mock.MatchedBy(func(b []byte) bool {
+		msg, err := model.ParseServerMessage(string(b))
+		return err == nil && msg.MessageType == model.MessageTypeError && msg.MessageString == "no matching quote"
+	})).Return(nil).Once()
+
+	a := &App{
+		server:  serverMock,
+		storage: storageMock,
+	}
+	if err := a.sendWOW(context.Background(), tConn, "uid", model.QuoteRequest{QuoteFilter: filter}, 21); !errors.Is(err, storage.ErrNoMatchingQuote) {
+		t.Errorf("App.sendWOW() error = %v, want %v", err, storage.ErrNoMatchingQuote)
+	}
+	serverMock.AssertExpectations(t)
+}
+
+func Test_clientIdentity(t *testing.T) {
+	t.Parallel()
+
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
+
+	tests := []struct {
+		name string
+		req  model.QuoteRequest
+		want string
+	}{
+		{
+			name: "Client ID of a signed challenge",
+			req:  model.QuoteRequest{ClientID: "dashboard-1"},
+			want: "dashboard-1",
+		},
+		{
+			name: "Address",
+			req:  model.QuoteRequest{},
+			want: "pipe",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clientIdentity(conn, tt.req); got != tt.want {
+				t.Errorf("clientIdentity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_quoteRequest(t *testing.T) {
+	t.Parallel()
+
+	filter := &model.QuoteFilter{Author: "Seneca"}
+
+	tests := []struct {
+		name    string
+		message model.Message
+		want    model.QuoteRequest
+	}{
+		{
+			name:    "Random quote",
+			message: model.Message{MessageType: model.MessageTypeRequest},
+			want:    model.QuoteRequest{},
+		},
+		{
+			name:    "Filtered quote",
+			message: model.Message{MessageType: model.MessageTypeRequest, Filter: filter},
+			want:    model.QuoteRequest{QuoteFilter: *filter},
+		},
+		{
+			name:    "Quote of the day ignores filter",
+			message: model.Message{MessageType: model.MessageTypeDaily, Filter: filter},
+			want:    model.QuoteRequest{Daily: true},
+		},
+		{
+			name:    "Client ID",
+			message: model.Message{MessageType: model.MessageTypeRequest, ClientID: "dashboard-1"},
+			want:    model.QuoteRequest{ClientID: "dashboard-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quoteRequest(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("quoteRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_sendWOW_daily(t *testing.T) {
+	t.Parallel()
+
+	config.InitLogger()
+	tConn := *new(net.Conn)
+	quote := model.Quote{ID: "q1", Text: "Random Word of Wisdom"}
+
+	serverMock := &serverMocks.ServerProvider{}
+	storageMock := &storageMocks.Storageer{}
+	storageMock.On("GetDailyQuote", mock.Anything, quoteDay(time.Now())).Return(quote, nil).Once()
+	serverMock.On("SendMessage", mock.Anything, tConn, mock.Anything).Return(nil).Once()
+
+	a := &App{
+		server:  serverMock,
+		storage: storageMock,
+	}
+	if err := a.sendWOW(context.Background(), tConn, "uid", model.QuoteRequest{Daily: true}, 21); err != nil {
+		t.Errorf("App.sendWOW() error = %v", err)
+	}
+	storageMock.AssertExpectations(t)
+}
+
+func Test_quoteDay(t *testing.T) {
+	now := time.Date(2024, 3, 1, 22, 30, 0, 0, time.UTC)
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	defer func() { config.Config.DailyLocation = nil }()
+
+	config.Config.DailyLocation = time.UTC
+	if got := quoteDay(now); got != "2024-03-01" {
+		t.Errorf("quoteDay() in UTC = %v, want %v", got, "2024-03-01")
+	}
+	config.Config.DailyLocation = moscow
+	if got := quoteDay(now); got != "2024-03-02" {
+		t.Errorf("quoteDay() in Moscow = %v, want %v", got, "2024-03-02")
+	}
+}
+
+func TestApp_Run_ready(t *testing.T) {
+	t.Parallel()
+	config.InitLogger()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	serverMock := &serverMocks.ServerProvider{}
+	serverMock.On("Run", mock.Anything).Return(listener, nil)
+
+	a := &App{server: serverMock}
+	if a.Ready() {
+		t.Fatal("App.Ready() = true before Run")
+	}
+
+	done := make(chan error)
+	go func() { done <- a.Run(context.Background()) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !a.Ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("App.Ready() = false after the listener is bound")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	listener.Close()
+	<-done
+	if a.Ready() {
+		t.Error("App.Ready() = true after Run returned")
+	}
+}
+
+// addrConn is a connection from a given remote address.
+type addrConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (c addrConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func TestApp_reputation(t *testing.T) {
+	t.Parallel()
+	config.InitLogger()
+	ctx := context.Background()
+
+	tracker := reputation.New(reputation.Policy{
+		Failed:       5,
+		Malformed:    10,
+		PointsPerBit: 5,
+		MaxPenalty:   8,
+		IPv4Prefix:   24,
+		IPv6Prefix:   64,
+	})
+
+	var sent []model.Message
+	serverMock := &serverMocks.ServerProvider{}
+	serverMock.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		msg, err := model.ParseServerMessage(string(args.Get(2).([]byte)))
+		if err == nil {
+			sent = append(sent, msg)
+		}
+	}).Return(nil)
+	requeststoreMock := &storageMocks.Requester{}
+	requeststoreMock.On("Add", mock.Anything, mock.Anything, mock.Anything).Return()
+	requeststoreMock.On("Get", mock.Anything, mock.Anything).Return(`{"difficulty":10}`, nil)
+	requeststoreMock.On("Consume", mock.Anything, mock.Anything).Return(`{"difficulty":10}`, nil)
+	challengeMock := &mocks.Challenger{}
+	challengeMock.On("Difficulty").Return(10)
+	challengeMock.On("Negotiate", mock.Anything, mock.Anything).Return(func(_ []string, difficulty int) (pow.Algorithm, int, error) {
+		return pow.Keccak{}, difficulty, nil
+	})
+	challengeMock.On("IsValid", mock.Anything, mock.Anything, pow.Issued{Difficulty: 10}).Return(false)
+
+	a := &App{
+		server:       serverMock,
+		storage:      &storageMocks.Storageer{},
+		requeststore: requeststoreMock,
+		challenge:    challengeMock,
+		reputation:   tracker,
+	}
+
+	// two invalid solutions and a malformed one
+	for _, solution := range []string{"2450", "2451", "answer"} {
+		clientResponse := model.Message{RequestID: storage.GenUID(), MessageType: model.MessageTypeSolution, MessageString: solution}
+		if _, err := a.validatePOW(ctx, clientResponse, "10.0.0.1", 21); err == nil {
+			t.Fatalf("App.validatePOW(%s) accepted an invalid solution", solution)
+		}
+	}
+
+	for _, tt := range []struct {
+		addr string
+		want int
+	}{
+		{addr: "10.0.0.1", want: 14},
+		{addr: "10.0.1.1", want: 10},
+	} {
+		conn := addrConn{addr: &net.TCPAddr{IP: net.ParseIP(tt.addr), Port: 40000}}
+		if _, err := a.sendChallenge(ctx, conn, model.QuoteRequest{}, nil, 21); err != nil {
+			t.Fatalf("App.sendChallenge() error = %v", err)
+		}
+		if got := sent[len(sent)-1].Difficulty; got != tt.want {
+			t.Errorf("difficulty of the challenge for %s = %d, want %d", tt.addr, got, tt.want)
+		}
+	}
+}
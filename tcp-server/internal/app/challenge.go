@@ -3,23 +3,35 @@ package app
 import (
 	"fmt"
 	"math/big"
+	"time"
 
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pullya/wow_tcp_server/shared/pow"
 )
 
+// Challenger sets the difficulty and the algorithm of issued challenges and verifies solutions.
+// A solution is checked against what its challenge was issued with, which may differ
+// from the current difficulty and epoch.
+//
 //go:generate mockery --name=Challenger --output=mocks --case=underscore
 type Challenger interface {
-	IsValid(challenge string, response uint64) bool
+	IsValid(challenge string, nonce uint64, issued pow.Issued) bool
+	// IsValidStamp verifies a hashcash stamp bound to the request id, telling why it's rejected.
+	IsValidStamp(requestID, stamp string, issued pow.Issued) error
 	Difficulty() int
+	// Negotiate picks the algorithm for a client that supports the listed ones and
+	// scales the difficulty to the cost of its hash.
+	Negotiate(supported []string, difficulty int) (pow.Algorithm, int, error)
 }
 
 type Challenge struct {
 	difficulty int
+	algorithms
 }
 
-func NewChallenge(difficulty int) Challenge {
+func NewChallenge(difficulty int, registry *pow.Registry) Challenge {
 	return Challenge{
 		difficulty: difficulty,
+		algorithms: algorithms{registry: registry},
 	}
 }
 
@@ -27,11 +39,51 @@ func (c Challenge) Difficulty() int {
 	return c.difficulty
 }
 
-func (c Challenge) IsValid(challenge string, nonce uint64) bool {
+// algorithms negotiates and verifies with the registered algorithms for both challengers.
+type algorithms struct {
+	registry *pow.Registry
+}
+
+func (a algorithms) Negotiate(supported []string, difficulty int) (pow.Algorithm, int, error) {
+	algorithm, offset, err := a.registry.Negotiate(supported)
+	if err != nil {
+		return nil, 0, err
+	}
+	return algorithm, max(difficulty-offset, 0), nil
+}
+
+func (a algorithms) IsValid(challenge string, nonce uint64, issued pow.Issued) bool {
+	algorithm, _, ok := a.registry.Get(issued.Algorithm)
+	if !ok {
+		return false
+	}
+	if _, ok := algorithm.(pow.Stamper); ok {
+		return false
+	}
+	if epoched, ok := algorithm.(pow.Epoched); ok {
+		algorithm = epoched.At(issued.Epoch)
+	}
+	return isValid(algorithm, challenge, nonce, issued.Difficulty)
+}
+
+func (a algorithms) IsValidStamp(requestID, stamp string, issued pow.Issued) error {
+	algorithm, _, ok := a.registry.Get(issued.Algorithm)
+	if !ok {
+		return fmt.Errorf("unknown pow algorithm '%s'", issued.Algorithm)
+	}
+	stamper, ok := algorithm.(pow.Stamper)
+	if !ok {
+		return fmt.Errorf("%s challenge is solved with a nonce", algorithm.Name())
+	}
+	return stamper.Verify(stamp, requestID, issued.Difficulty, time.Now())
+}
+
+// isValid reports whether the hash of the challenge and nonce has difficulty leading zero bits.
+func isValid(algorithm pow.Algorithm, challenge string, nonce uint64, difficulty int) bool {
 	target := new(big.Int)
-	target.Exp(big.NewInt(2), big.NewInt(int64(256-c.difficulty)), nil)
+	target.Exp(big.NewInt(2), big.NewInt(int64(256-difficulty)), nil)
 
-	hash := crypto.Keccak256([]byte(fmt.Sprint(challenge, nonce)))
+	hash := algorithm.Hash(challenge, nonce)
 	hashInt := new(big.Int).SetBytes(hash)
 
 	return hashInt.Cmp(target) == -1
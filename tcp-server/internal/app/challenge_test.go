@@ -0,0 +1,42 @@
+package app
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/shared/pow"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+)
+
+func TestChallenge_IsValidStamp(t *testing.T) {
+	t.Parallel()
+
+	hashcash, _ := pow.NewHashcash(model.HashSHA1, time.Minute)
+	registry := pow.NewRegistry()
+	registry.Register(pow.Keccak{}, 0)
+	registry.Register(hashcash, 0)
+	c := NewChallenge(8, registry)
+
+	stamp := pow.NewStamp("uid", 8, time.Now())
+	var nonce uint64
+	for pow.LeadingZeros(hashcash.Hash(stamp.Prefix(), nonce)) < stamp.Bits {
+		nonce++
+	}
+	stamp.Counter = strconv.FormatUint(nonce, 10)
+	issued := pow.Issued{Difficulty: 8, Algorithm: model.AlgorithmHashcash}
+
+	if err := c.IsValidStamp("uid", stamp.String(), issued); err != nil {
+		t.Errorf("Challenge.IsValidStamp() error = %v", err)
+	}
+	if err := c.IsValidStamp("other", stamp.String(), issued); !errors.Is(err, pow.ErrStampResource) {
+		t.Errorf("Challenge.IsValidStamp() of another challenge error = %v, want %v", err, pow.ErrStampResource)
+	}
+	if err := c.IsValidStamp("uid", stamp.String(), pow.Issued{Difficulty: 8}); err == nil {
+		t.Error("Challenge.IsValidStamp() accepted a stamp for a keccak challenge")
+	}
+	if c.IsValid("uid", nonce, issued) {
+		t.Error("Challenge.IsValid() accepted a nonce for a hashcash challenge")
+	}
+}
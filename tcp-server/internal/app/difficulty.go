@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"math"
+	runtimemetrics "runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/shared/pow"
+)
+
+// Load is a snapshot of the server load. Accepted and the CPU times are running
+// totals, the controller turns them into rates between two snapshots.
+type Load struct {
+	At       time.Time
+	Active   int64
+	Accepted int64
+	// CPUBusy and CPUTotal are the seconds of CPU time the process used and had available.
+	CPUBusy  float64
+	CPUTotal float64
+}
+
+// LoadLimits are the load levels at which the difficulty reaches its maximum.
+// A zero limit turns the signal off.
+type LoadLimits struct {
+	Connections int64
+	AcceptRate  float64
+	CPU         float64
+}
+
+// AdaptiveChallenge issues challenges with a difficulty that follows the server load:
+// min when idle, max once any signal reaches its limit and linear in between. The load
+// is smoothed with an exponential moving average, so short bursts don't swing it.
+type AdaptiveChallenge struct {
+	min       int
+	max       int
+	limits    LoadLimits
+	smoothing float64
+	algorithms
+
+	mu       sync.Mutex
+	pressure float64
+	last     *Load
+
+	difficulty atomic.Int64
+}
+
+// NewAdaptiveChallenge starts at the initial difficulty clamped to [min, max].
+// smoothing is the weight of the previous load in [0, 1), 0 follows the load immediately.
+func NewAdaptiveChallenge(min, max, initial int, limits LoadLimits, smoothing float64, registry *pow.Registry) *AdaptiveChallenge {
+	c := &AdaptiveChallenge{
+		min:        min,
+		max:        max,
+		limits:     limits,
+		smoothing:  smoothing,
+		algorithms: algorithms{registry: registry},
+	}
+	if max > min {
+		c.pressure = math.Min(math.Max(float64(initial-min)/float64(max-min), 0), 1)
+	}
+	c.set(c.pressure)
+	return c
+}
+
+func (c *AdaptiveChallenge) Difficulty() int {
+	return int(c.difficulty.Load())
+}
+
+// Update adjusts the difficulty to the load and returns the new one.
+func (c *AdaptiveChallenge) Update(load Load) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pressure := ratio(float64(load.Active), float64(c.limits.Connections))
+	if c.last != nil {
+		if elapsed := load.At.Sub(c.last.At).Seconds(); elapsed > 0 {
+			rate := float64(load.Accepted-c.last.Accepted) / elapsed
+			pressure = math.Max(pressure, ratio(rate, c.limits.AcceptRate))
+		}
+		if total := load.CPUTotal - c.last.CPUTotal; total > 0 {
+			usage := (load.CPUBusy - c.last.CPUBusy) / total
+			pressure = math.Max(pressure, ratio(usage, c.limits.CPU))
+		}
+	}
+	c.last = &load
+
+	c.pressure = c.smoothing*c.pressure + (1-c.smoothing)*math.Min(pressure, 1)
+	return c.set(c.pressure)
+}
+
+// Run samples the load every interval until ctx is done.
+func (c *AdaptiveChallenge) Run(ctx context.Context, interval time.Duration, sample func() Load) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Update(sample())
+		}
+	}
+}
+
+func (c *AdaptiveChallenge) set(pressure float64) int {
+	difficulty := c.min + int(math.Round(float64(c.max-c.min)*pressure))
+	c.difficulty.Store(int64(difficulty))
+	return difficulty
+}
+
+func ratio(value, limit float64) float64 {
+	if limit <= 0 {
+		return 0
+	}
+	return value / limit
+}
+
+var cpuSamples = []runtimemetrics.Sample{
+	{Name: "/cpu/classes/total:cpu-seconds"},
+	{Name: "/cpu/classes/idle:cpu-seconds"},
+}
+
+// cpuTimes estimates the CPU seconds the process used and had available since start.
+func cpuTimes() (busy float64, total float64) {
+	samples := make([]runtimemetrics.Sample, len(cpuSamples))
+	copy(samples, cpuSamples)
+	runtimemetrics.Read(samples)
+
+	for _, s := range samples {
+		if s.Value.Kind() != runtimemetrics.KindFloat64 {
+			return 0, 0
+		}
+	}
+	total = samples[0].Value.Float64()
+	return total - samples[1].Value.Float64(), total
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/shared/pow"
+)
+
+// simulation replays a load pattern against the controller, one sample per second.
+type simulation struct {
+	c    *AdaptiveChallenge
+	now  time.Time
+	load Load
+}
+
+func keccakRegistry() *pow.Registry {
+	registry := pow.NewRegistry()
+	registry.Register(pow.Keccak{}, 0)
+	return registry
+}
+
+func newSimulation(c *AdaptiveChallenge) *simulation {
+	s := &simulation{c: c, now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s.load.At = s.now
+	c.Update(s.load)
+	return s
+}
+
+// step advances one second with active connections, accepts per second and CPU usage
+// as a share of the available CPU, and returns the difficulty.
+func (s *simulation) step(active int64, rate int64, cpu float64) int {
+	s.now = s.now.Add(time.Second)
+	s.load = Load{
+		At:       s.now,
+		Active:   active,
+		Accepted: s.load.Accepted + rate,
+		CPUBusy:  s.load.CPUBusy + cpu*4,
+		CPUTotal: s.load.CPUTotal + 4,
+	}
+	return s.c.Update(s.load)
+}
+
+func TestAdaptiveChallenge_flood(t *testing.T) {
+	t.Parallel()
+
+	limits := LoadLimits{Connections: 200, AcceptRate: 100, CPU: 0.8}
+	s := newSimulation(NewAdaptiveChallenge(8, 24, 8, limits, 0.7, keccakRegistry()))
+
+	for i := 0; i < 30; i++ {
+		if got := s.step(2, 1, 0.01); got != 8 {
+			t.Fatalf("idle second %d: difficulty = %d, want 8", i, got)
+		}
+	}
+
+	prev := s.c.Difficulty()
+	for i := 0; i < 30; i++ {
+		got := s.step(1000, 5000, 1)
+		if got < prev {
+			t.Fatalf("flood second %d: difficulty dropped from %d to %d", i, prev, got)
+		}
+		prev = got
+	}
+	if prev != 24 {
+		t.Errorf("difficulty after flood = %d, want 24", prev)
+	}
+
+	for i := 0; i < 30; i++ {
+		got := s.step(2, 1, 0.01)
+		if got > prev {
+			t.Fatalf("cooldown second %d: difficulty rose from %d to %d", i, prev, got)
+		}
+		prev = got
+	}
+	if prev != 8 {
+		t.Errorf("difficulty after cooldown = %d, want 8", prev)
+	}
+}
+
+func TestAdaptiveChallenge_signals(t *testing.T) {
+	t.Parallel()
+
+	limits := LoadLimits{Connections: 100, AcceptRate: 50, CPU: 0.5}
+	tests := []struct {
+		name   string
+		active int64
+		rate   int64
+		cpu    float64
+		want   int
+	}{
+		{
+			name: "Idle",
+			want: 4,
+		},
+		{
+			name:   "Half of connections limit",
+			active: 50,
+			want:   12,
+		},
+		{
+			name: "Accept rate at limit",
+			rate: 50,
+			want: 20,
+		},
+		{
+			name: "CPU over limit",
+			cpu:  0.9,
+			want: 20,
+		},
+		{
+			name:   "Highest signal wins",
+			active: 25,
+			rate:   25,
+			cpu:    0.05,
+			want:   12,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSimulation(NewAdaptiveChallenge(4, 20, 4, limits, 0, keccakRegistry()))
+			if got := s.step(tt.active, tt.rate, tt.cpu); got != tt.want {
+				t.Errorf("AdaptiveChallenge.Update() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdaptiveChallenge_smoothing(t *testing.T) {
+	t.Parallel()
+
+	limits := LoadLimits{AcceptRate: 10}
+	s := newSimulation(NewAdaptiveChallenge(0, 20, 0, limits, 0.9, keccakRegistry()))
+
+	spike := s.step(0, 1000, 0)
+	if spike != 2 {
+		t.Errorf("difficulty after a one second spike = %d, want 2", spike)
+	}
+	for i := 0; i < 60; i++ {
+		s.step(0, 0, 0)
+	}
+	if got := s.c.Difficulty(); got != 0 {
+		t.Errorf("difficulty after the spike faded = %d, want 0", got)
+	}
+}
+
+func TestAdaptiveChallenge_bounds(t *testing.T) {
+	t.Parallel()
+
+	rnd := rand.New(rand.NewSource(1))
+	limits := LoadLimits{Connections: 100, AcceptRate: 100, CPU: 0.8}
+	c := NewAdaptiveChallenge(10, 16, 30, limits, 0.5, keccakRegistry())
+	if got := c.Difficulty(); got != 16 {
+		t.Errorf("initial difficulty = %d, want it clamped to 16", got)
+	}
+
+	s := newSimulation(c)
+	for i := 0; i < 1000; i++ {
+		got := s.step(rnd.Int63n(1000), rnd.Int63n(1000), rnd.Float64())
+		if got < 10 || got > 16 {
+			t.Fatalf("step %d: difficulty = %d, want within [10, 16]", i, got)
+		}
+	}
+}
+
+func TestAdaptiveChallenge_IsValid(t *testing.T) {
+	t.Parallel()
+
+	c := NewAdaptiveChallenge(4, 64, 4, LoadLimits{Connections: 1}, 0, keccakRegistry())
+	challenge := generatePOWChallenge("request")
+	var nonce uint64
+	for !c.IsValid(challenge, nonce, pow.Issued{Difficulty: c.Difficulty()}) {
+		nonce++
+	}
+
+	// the challenge stays solvable at the difficulty it was issued with
+	c.Update(Load{At: time.Now(), Active: 1})
+	if c.Difficulty() != 64 {
+		t.Fatalf("difficulty = %d, want 64", c.Difficulty())
+	}
+	if !c.IsValid(challenge, nonce, pow.Issued{Difficulty: 4}) {
+		t.Error("IsValid() rejected a solution at the issued difficulty")
+	}
+	if c.IsValid(challenge, nonce, pow.Issued{Difficulty: c.Difficulty()}) {
+		t.Error("IsValid() accepted a solution at the raised difficulty")
+	}
+}
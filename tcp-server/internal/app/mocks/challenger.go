@@ -2,7 +2,10 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	pow "github.com/pullya/wow_tcp_server/shared/pow"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // Challenger is an autogenerated mock type for the Challenger type
 type Challenger struct {
@@ -27,17 +30,17 @@ func (_m *Challenger) Difficulty() int {
 	return r0
 }
 
-// IsValid provides a mock function with given fields: challenge, response
-func (_m *Challenger) IsValid(challenge string, response uint64) bool {
-	ret := _m.Called(challenge, response)
+// IsValid provides a mock function with given fields: challenge, nonce, issued
+func (_m *Challenger) IsValid(challenge string, nonce uint64, issued pow.Issued) bool {
+	ret := _m.Called(challenge, nonce, issued)
 
 	if len(ret) == 0 {
 		panic("no return value specified for IsValid")
 	}
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(string, uint64) bool); ok {
-		r0 = rf(challenge, response)
+	if rf, ok := ret.Get(0).(func(string, uint64, pow.Issued) bool); ok {
+		r0 = rf(challenge, nonce, issued)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
@@ -45,6 +48,61 @@ func (_m *Challenger) IsValid(challenge string, response uint64) bool {
 	return r0
 }
 
+// IsValidStamp provides a mock function with given fields: requestID, stamp, issued
+func (_m *Challenger) IsValidStamp(requestID string, stamp string, issued pow.Issued) error {
+	ret := _m.Called(requestID, stamp, issued)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsValidStamp")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, pow.Issued) error); ok {
+		r0 = rf(requestID, stamp, issued)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Negotiate provides a mock function with given fields: supported, difficulty
+func (_m *Challenger) Negotiate(supported []string, difficulty int) (pow.Algorithm, int, error) {
+	ret := _m.Called(supported, difficulty)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Negotiate")
+	}
+
+	var r0 pow.Algorithm
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func([]string, int) (pow.Algorithm, int, error)); ok {
+		return rf(supported, difficulty)
+	}
+	if rf, ok := ret.Get(0).(func([]string, int) pow.Algorithm); ok {
+		r0 = rf(supported, difficulty)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pow.Algorithm)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]string, int) int); ok {
+		r1 = rf(supported, difficulty)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func([]string, int) error); ok {
+		r2 = rf(supported, difficulty)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // NewChallenger creates a new instance of Challenger. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewChallenger(t interface {
@@ -3,8 +3,11 @@ package config
 import (
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -27,8 +30,107 @@ const (
 	envDifficulty  = "WOW_SERVER_DIFFICULTY"
 	envProofString = "WOW_SERVER_PROOF_STRING"
 	envLogLevel    = "WOW_SERVER_LOG_LEVEL"
+	envMode        = "WOW_SERVER_MODE"
+	envStepTimeout = "WOW_SERVER_STEP_TIMEOUT"
 
-	shardsCount = 8
+	envChallengeMode   = "WOW_SERVER_CHALLENGE_MODE"
+	envChallengeSecret = "WOW_SERVER_CHALLENGE_SECRET"
+	envChallengeTTL    = "WOW_SERVER_CHALLENGE_TTL"
+	envSolvedRetention = "WOW_SERVER_SOLVED_RETENTION"
+	envSweepInterval   = "WOW_SERVER_SWEEP_INTERVAL"
+	envShardsCnt       = "WOW_SERVER_SHARDS_CNT"
+	envRequestStore    = "WOW_SERVER_REQUEST_STORE"
+	envRequestStoreDB  = "WOW_SERVER_REQUEST_STORE_PATH"
+	envRedisAddress    = "WOW_SERVER_REDIS_ADDRESS"
+	envRedisPassword   = "WOW_SERVER_REDIS_PASSWORD"
+	envRedisKeyPrefix  = "WOW_SERVER_REDIS_KEY_PREFIX"
+	envReplayDetector  = "WOW_SERVER_REPLAY_DETECTOR"
+	envBloomCapacity   = "WOW_SERVER_BLOOM_CAPACITY"
+	envBloomFPRate     = "WOW_SERVER_BLOOM_FALSE_POSITIVE_RATE"
+	envBloomWindow     = "WOW_SERVER_BLOOM_WINDOW"
+	envQuotesFile      = "WOW_SERVER_QUOTES_FILE"
+	envQuotesReload    = "WOW_SERVER_QUOTES_RELOAD_INTERVAL"
+	envQuoteStorage    = "WOW_SERVER_QUOTE_STORAGE"
+	envQuoteStorageDB  = "WOW_SERVER_QUOTE_STORAGE_PATH"
+	envQuoteRotation   = "WOW_SERVER_QUOTE_ROTATION"
+	envRotationHistory = "WOW_SERVER_ROTATION_HISTORY_SIZE"
+	envRotationTTL     = "WOW_SERVER_ROTATION_TTL"
+	envRotationClients = "WOW_SERVER_ROTATION_MAX_CLIENTS"
+	envDailyTimezone   = "WOW_SERVER_DAILY_TIMEZONE"
+	envAdminEnabled    = "WOW_SERVER_ADMIN_ENABLED"
+	envAdminAddress    = "WOW_SERVER_ADMIN_ADDRESS"
+	envAdminToken      = "WOW_SERVER_ADMIN_TOKEN"
+	envMetricsEnabled  = "WOW_SERVER_METRICS_ENABLED"
+	envMetricsAddress  = "WOW_SERVER_METRICS_ADDRESS"
+	envHealthAddress   = "WOW_SERVER_HEALTH_ADDRESS"
+	envTracingExporter = "WOW_SERVER_TRACING_EXPORTER"
+	envTracingEndpoint = "WOW_SERVER_TRACING_ENDPOINT"
+	envAdaptive        = "WOW_SERVER_ADAPTIVE_DIFFICULTY"
+	envMinDifficulty   = "WOW_SERVER_MIN_DIFFICULTY"
+	envMaxDifficulty   = "WOW_SERVER_MAX_DIFFICULTY"
+	envDiffInterval    = "WOW_SERVER_DIFFICULTY_INTERVAL"
+	envDiffSmoothing   = "WOW_SERVER_DIFFICULTY_SMOOTHING"
+	envLoadMaxConns    = "WOW_SERVER_LOAD_MAX_CONNECTIONS"
+	envLoadMaxRate     = "WOW_SERVER_LOAD_MAX_ACCEPT_RATE"
+	envLoadMaxCPU      = "WOW_SERVER_LOAD_MAX_CPU"
+	envReputation      = "WOW_SERVER_REPUTATION_ENABLED"
+	envRepHalfLife     = "WOW_SERVER_REPUTATION_HALF_LIFE"
+	envRepRequest      = "WOW_SERVER_REPUTATION_REQUEST_SCORE"
+	envRepSolved       = "WOW_SERVER_REPUTATION_SOLVED_SCORE"
+	envRepFailed       = "WOW_SERVER_REPUTATION_FAILED_SCORE"
+	envRepMalformed    = "WOW_SERVER_REPUTATION_MALFORMED_SCORE"
+	envRepPointsPerBit = "WOW_SERVER_REPUTATION_POINTS_PER_BIT"
+	envRepMaxPenalty   = "WOW_SERVER_REPUTATION_MAX_PENALTY"
+	envRepMaxBonus     = "WOW_SERVER_REPUTATION_MAX_BONUS"
+	envRepSubnetWeight = "WOW_SERVER_REPUTATION_SUBNET_WEIGHT"
+	envRepIPv4Prefix   = "WOW_SERVER_REPUTATION_IPV4_PREFIX"
+	envRepIPv6Prefix   = "WOW_SERVER_REPUTATION_IPV6_PREFIX"
+	envPowAlgorithms   = "WOW_SERVER_POW_ALGORITHMS"
+	envPowOffsets      = "WOW_SERVER_POW_DIFFICULTY_OFFSETS"
+	envArgon2Time      = "WOW_SERVER_ARGON2_TIME"
+	envArgon2Memory    = "WOW_SERVER_ARGON2_MEMORY"
+	envArgon2Threads   = "WOW_SERVER_ARGON2_THREADS"
+	envScryptN         = "WOW_SERVER_SCRYPT_N"
+	envScryptR         = "WOW_SERVER_SCRYPT_R"
+	envScryptP         = "WOW_SERVER_SCRYPT_P"
+	envEthashCache     = "WOW_SERVER_ETHASH_CACHE_SIZE"
+	envEthashDataset   = "WOW_SERVER_ETHASH_DATASET_SIZE"
+	envEthashEpoch     = "WOW_SERVER_ETHASH_EPOCH_LENGTH"
+	envHashcashHash    = "WOW_SERVER_HASHCASH_HASH"
+	envHashcashWindow  = "WOW_SERVER_HASHCASH_WINDOW"
+
+	ModeSession = "session"
+	ModeLegacy  = "legacy"
+
+	ChallengeModeStored = "stored"
+	ChallengeModeSigned = "signed"
+
+	RequestStoreMemory = "memory"
+	RequestStoreBolt   = "bolt"
+	RequestStoreRedis  = "redis"
+
+	ReplayDetectorStore = "store"
+	ReplayDetectorBloom = "bloom"
+
+	QuoteStorageMemory = "memory"
+	QuoteStorageSQLite = "sqlite"
+
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+
+	AlgorithmKeccak   = "keccak"
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmScrypt   = "scrypt"
+	AlgorithmEthash   = "ethash"
+	AlgorithmHashcash = "hashcash"
+
+	HashcashSHA1   = "sha1"
+	HashcashSHA256 = "sha256"
+
+	shardsCount         = 8
+	rotationHistorySize = 100
+	rotationMaxClients  = 100000
 )
 
 var Config Configuration
@@ -50,6 +152,73 @@ var envArray = []string{
 	envDifficulty,
 	envProofString,
 	envLogLevel,
+	envMode,
+	envStepTimeout,
+	envChallengeMode,
+	envChallengeSecret,
+	envChallengeTTL,
+	envSolvedRetention,
+	envSweepInterval,
+	envShardsCnt,
+	envRequestStore,
+	envRequestStoreDB,
+	envRedisAddress,
+	envRedisPassword,
+	envRedisKeyPrefix,
+	envReplayDetector,
+	envBloomCapacity,
+	envBloomFPRate,
+	envBloomWindow,
+	envQuotesFile,
+	envQuotesReload,
+	envQuoteStorage,
+	envQuoteStorageDB,
+	envQuoteRotation,
+	envRotationHistory,
+	envRotationTTL,
+	envRotationClients,
+	envDailyTimezone,
+	envAdminEnabled,
+	envAdminAddress,
+	envAdminToken,
+	envMetricsEnabled,
+	envMetricsAddress,
+	envHealthAddress,
+	envTracingExporter,
+	envTracingEndpoint,
+	envAdaptive,
+	envMinDifficulty,
+	envMaxDifficulty,
+	envDiffInterval,
+	envDiffSmoothing,
+	envLoadMaxConns,
+	envLoadMaxRate,
+	envLoadMaxCPU,
+	envReputation,
+	envRepHalfLife,
+	envRepRequest,
+	envRepSolved,
+	envRepFailed,
+	envRepMalformed,
+	envRepPointsPerBit,
+	envRepMaxPenalty,
+	envRepMaxBonus,
+	envRepSubnetWeight,
+	envRepIPv4Prefix,
+	envRepIPv6Prefix,
+	envPowAlgorithms,
+	envPowOffsets,
+	envArgon2Time,
+	envArgon2Memory,
+	envArgon2Threads,
+	envScryptN,
+	envScryptR,
+	envScryptP,
+	envEthashCache,
+	envEthashDataset,
+	envEthashEpoch,
+	envHashcashHash,
+	envHashcashWindow,
 }
 
 type LogLevel string
@@ -59,11 +228,95 @@ type Configuration struct {
 	Timeout     int    `yaml:"timeout"`
 	ServiceName string `yaml:"serviceName"`
 
+	Mode        string `yaml:"mode"`
+	StepTimeout int    `yaml:"stepTimeout"`
+
 	Difficulty  int    `yaml:"difficulty"`
 	ProofString string `yaml:"proofString"`
 
+	PowAlgorithms        []string       `yaml:"powAlgorithms"`
+	PowDifficultyOffsets map[string]int `yaml:"powDifficultyOffsets"`
+	Argon2Time           int            `yaml:"argon2Time"`
+	Argon2Memory         int            `yaml:"argon2Memory"`
+	Argon2Threads        int            `yaml:"argon2Threads"`
+	ScryptN              int            `yaml:"scryptN"`
+	ScryptR              int            `yaml:"scryptR"`
+	ScryptP              int            `yaml:"scryptP"`
+
+	EthashCacheSize   int `yaml:"ethashCacheSize"`
+	EthashDatasetSize int `yaml:"ethashDatasetSize"`
+	EthashEpochLength int `yaml:"ethashEpochLength"`
+
+	HashcashHash   string `yaml:"hashcashHash"`
+	HashcashWindow int    `yaml:"hashcashWindow"`
+
+	AdaptiveDifficulty  bool    `yaml:"adaptiveDifficulty"`
+	MinDifficulty       int     `yaml:"minDifficulty"`
+	MaxDifficulty       int     `yaml:"maxDifficulty"`
+	DifficultyInterval  int     `yaml:"difficultyInterval"`
+	DifficultySmoothing float64 `yaml:"difficultySmoothing"`
+	LoadMaxConnections  int     `yaml:"loadMaxConnections"`
+	LoadMaxAcceptRate   float64 `yaml:"loadMaxAcceptRate"`
+	LoadMaxCPU          float64 `yaml:"loadMaxCPU"`
+
+	ReputationEnabled        bool    `yaml:"reputationEnabled"`
+	ReputationHalfLife       int     `yaml:"reputationHalfLife"`
+	ReputationRequestScore   float64 `yaml:"reputationRequestScore"`
+	ReputationSolvedScore    float64 `yaml:"reputationSolvedScore"`
+	ReputationFailedScore    float64 `yaml:"reputationFailedScore"`
+	ReputationMalformedScore float64 `yaml:"reputationMalformedScore"`
+	ReputationPointsPerBit   float64 `yaml:"reputationPointsPerBit"`
+	ReputationMaxPenalty     int     `yaml:"reputationMaxPenalty"`
+	ReputationMaxBonus       int     `yaml:"reputationMaxBonus"`
+	ReputationSubnetWeight   float64 `yaml:"reputationSubnetWeight"`
+	ReputationIPv4Prefix     int     `yaml:"reputationIPv4Prefix"`
+	ReputationIPv6Prefix     int     `yaml:"reputationIPv6Prefix"`
+
+	ChallengeMode   string `yaml:"challengeMode"`
+	ChallengeSecret string `yaml:"challengeSecret"`
+	ChallengeTTL    int    `yaml:"challengeTTL"`
+	SolvedRetention int    `yaml:"solvedRetention"`
+	SweepInterval   int    `yaml:"sweepInterval"`
+
 	ShardsCnt int `yaml:"shardsCnt"`
 
+	RequestStore     string `yaml:"requestStore"`
+	RequestStorePath string `yaml:"requestStorePath"`
+
+	RedisAddress   string `yaml:"redisAddress"`
+	RedisPassword  string `yaml:"redisPassword"`
+	RedisKeyPrefix string `yaml:"redisKeyPrefix"`
+
+	ReplayDetector         string  `yaml:"replayDetector"`
+	BloomCapacity          int     `yaml:"bloomCapacity"`
+	BloomFalsePositiveRate float64 `yaml:"bloomFalsePositiveRate"`
+	BloomWindow            int     `yaml:"bloomWindow"`
+
+	QuotesFile           string `yaml:"quotesFile"`
+	QuotesReloadInterval int    `yaml:"quotesReloadInterval"`
+	QuoteStorage         string `yaml:"quoteStorage"`
+	QuoteStoragePath     string `yaml:"quoteStoragePath"`
+
+	QuoteRotation       bool `yaml:"quoteRotation"`
+	RotationHistorySize int  `yaml:"rotationHistorySize"`
+	RotationTTL         int  `yaml:"rotationTTL"`
+	RotationMaxClients  int  `yaml:"rotationMaxClients"`
+
+	DailyTimezone string         `yaml:"dailyTimezone"`
+	DailyLocation *time.Location `yaml:"-"`
+
+	AdminEnabled bool   `yaml:"adminEnabled"`
+	AdminAddress string `yaml:"adminAddress"`
+	AdminToken   string `yaml:"adminToken"`
+
+	MetricsEnabled bool   `yaml:"metricsEnabled"`
+	MetricsAddress string `yaml:"metricsAddress"`
+
+	HealthAddress string `yaml:"healthAddress"`
+
+	TracingExporter string `yaml:"tracingExporter"`
+	TracingEndpoint string `yaml:"tracingEndpoint"`
+
 	LogLevel LogLevel `yaml:"logLevel"`
 }
 
@@ -81,11 +334,75 @@ func ReadConfig() {
 		log.Fatalf("failed to unmarshall config file '%s', error: %v", configFile, err)
 		return
 	}
-	Config.ShardsCnt = shardsCount
+	if Config.ShardsCnt <= 0 {
+		Config.ShardsCnt = shardsCount
+	}
+	if Config.RotationHistorySize <= 0 {
+		Config.RotationHistorySize = rotationHistorySize
+	}
+	if Config.RotationMaxClients <= 0 {
+		Config.RotationMaxClients = rotationMaxClients
+	}
+	if Config.Mode == "" {
+		Config.Mode = ModeLegacy
+	}
+	if Config.ChallengeMode == "" {
+		Config.ChallengeMode = ChallengeModeStored
+	}
+	if Config.ReplayDetector == "" {
+		Config.ReplayDetector = ReplayDetectorStore
+	}
 
 	log.Debugf("Default configuration read: %v", Config)
 
 	checkEnv()
+
+	if Config.DailyTimezone == "" {
+		Config.DailyTimezone = "UTC"
+	}
+	Config.DailyLocation, err = validateTimezone(Config.DailyTimezone)
+	if err != nil {
+		log.Fatalf("failed to load dailyTimezone '%s', error: %v", Config.DailyTimezone, err)
+	}
+
+	if err := Config.validate(); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+}
+
+// validate checks the settings that depend on each other, and the values of config.yaml,
+// which unlike env vars aren't checked one by one.
+func (c *Configuration) validate() error {
+	if _, err := validateMode(c.Mode); err != nil {
+		return fmt.Errorf("mode '%s': %w", c.Mode, err)
+	}
+	if _, err := validateChallengeMode(c.ChallengeMode); err != nil {
+		return fmt.Errorf("challengeMode '%s': %w", c.ChallengeMode, err)
+	}
+	if _, err := validateReplayDetector(c.ReplayDetector); err != nil {
+		return fmt.Errorf("replayDetector '%s': %w", c.ReplayDetector, err)
+	}
+	if c.ChallengeTTL <= 0 {
+		return errors.New("challengeTTL must be positive")
+	}
+	if c.SolvedRetention <= 0 {
+		return errors.New("solvedRetention must be positive")
+	}
+	if c.QuoteRotation && c.RotationTTL <= 0 {
+		return errors.New("rotationTTL must be positive")
+	}
+	if c.ReplayDetector == ReplayDetectorBloom {
+		if c.ChallengeMode != ChallengeModeSigned {
+			return errors.New("replayDetector 'bloom' is only used with challengeMode 'signed'")
+		}
+		if c.BloomCapacity <= 0 {
+			return errors.New("bloomCapacity must be positive")
+		}
+		if c.BloomFalsePositiveRate <= 0 || c.BloomFalsePositiveRate >= 1 {
+			return errors.New("bloomFalsePositiveRate must be between 0 and 1")
+		}
+	}
+	return nil
 }
 
 func (l LogLevel) ToLogrusFormat() log.Level {
@@ -130,6 +447,371 @@ func checkEnv() {
 					Config.LogLevel = ll
 					log.Debugf("logLevel set to '%v'", Config.LogLevel)
 				}
+			case envMode:
+				mode, err := validateMode(envVal)
+				if err == nil {
+					Config.Mode = mode
+					log.Debugf("mode set to '%s'", Config.Mode)
+				}
+			case envStepTimeout:
+				timeout, err := validateTimeout(envVal)
+				if err == nil {
+					Config.StepTimeout = timeout
+					log.Debugf("stepTimeout set to %d", Config.StepTimeout)
+				}
+			case envChallengeMode:
+				mode, err := validateChallengeMode(envVal)
+				if err == nil {
+					Config.ChallengeMode = mode
+					log.Debugf("challengeMode set to '%s'", Config.ChallengeMode)
+				}
+			case envChallengeSecret:
+				Config.ChallengeSecret = envVal
+				log.Debug("challengeSecret set")
+			case envChallengeTTL:
+				ttl, err := validateTimeout(envVal)
+				if err == nil {
+					Config.ChallengeTTL = ttl
+					log.Debugf("challengeTTL set to %d", Config.ChallengeTTL)
+				}
+			case envSolvedRetention:
+				retention, err := validateTimeout(envVal)
+				if err == nil {
+					Config.SolvedRetention = retention
+					log.Debugf("solvedRetention set to %d", Config.SolvedRetention)
+				}
+			case envSweepInterval:
+				interval, err := validateTimeout(envVal)
+				if err == nil && interval > 0 {
+					Config.SweepInterval = interval
+					log.Debugf("sweepInterval set to %d", Config.SweepInterval)
+				}
+			case envShardsCnt:
+				cnt, err := validateShardsCnt(envVal)
+				if err == nil {
+					Config.ShardsCnt = cnt
+					log.Debugf("shardsCnt set to %d", Config.ShardsCnt)
+				}
+			case envRequestStore:
+				rs, err := validateRequestStore(envVal)
+				if err == nil {
+					Config.RequestStore = rs
+					log.Debugf("requestStore set to '%s'", Config.RequestStore)
+				}
+			case envRequestStoreDB:
+				Config.RequestStorePath = envVal
+				log.Debugf("requestStorePath set to '%s'", Config.RequestStorePath)
+			case envRedisAddress:
+				Config.RedisAddress = envVal
+				log.Debugf("redisAddress set to '%s'", Config.RedisAddress)
+			case envRedisPassword:
+				Config.RedisPassword = envVal
+				log.Debug("redisPassword set")
+			case envRedisKeyPrefix:
+				Config.RedisKeyPrefix = envVal
+				log.Debugf("redisKeyPrefix set to '%s'", Config.RedisKeyPrefix)
+			case envReplayDetector:
+				rd, err := validateReplayDetector(envVal)
+				if err == nil {
+					Config.ReplayDetector = rd
+					log.Debugf("replayDetector set to '%s'", Config.ReplayDetector)
+				}
+			case envBloomCapacity:
+				capacity, err := validatePositiveInt("bloomCapacity", envVal)
+				if err == nil {
+					Config.BloomCapacity = capacity
+					log.Debugf("bloomCapacity set to %d", Config.BloomCapacity)
+				}
+			case envBloomFPRate:
+				rate, err := validateFalsePositiveRate(envVal)
+				if err == nil {
+					Config.BloomFalsePositiveRate = rate
+					log.Debugf("bloomFalsePositiveRate set to %v", Config.BloomFalsePositiveRate)
+				}
+			case envBloomWindow:
+				window, err := validateTimeout(envVal)
+				if err == nil {
+					Config.BloomWindow = window
+					log.Debugf("bloomWindow set to %d", Config.BloomWindow)
+				}
+			case envQuotesFile:
+				Config.QuotesFile = envVal
+				log.Debugf("quotesFile set to '%s'", Config.QuotesFile)
+			case envQuotesReload:
+				interval, err := validateTimeout(envVal)
+				if err == nil {
+					Config.QuotesReloadInterval = interval
+					log.Debugf("quotesReloadInterval set to %d", Config.QuotesReloadInterval)
+				}
+			case envQuoteStorage:
+				qs, err := validateQuoteStorage(envVal)
+				if err == nil {
+					Config.QuoteStorage = qs
+					log.Debugf("quoteStorage set to '%s'", Config.QuoteStorage)
+				}
+			case envQuoteStorageDB:
+				Config.QuoteStoragePath = envVal
+				log.Debugf("quoteStoragePath set to '%s'", Config.QuoteStoragePath)
+			case envQuoteRotation:
+				rotation, err := strconv.ParseBool(envVal)
+				if err == nil {
+					Config.QuoteRotation = rotation
+					log.Debugf("quoteRotation set to %v", Config.QuoteRotation)
+				}
+			case envRotationHistory:
+				size, err := validatePositiveInt("rotationHistorySize", envVal)
+				if err == nil {
+					Config.RotationHistorySize = size
+					log.Debugf("rotationHistorySize set to %d", Config.RotationHistorySize)
+				}
+			case envRotationTTL:
+				ttl, err := validateTimeout(envVal)
+				if err == nil {
+					Config.RotationTTL = ttl
+					log.Debugf("rotationTTL set to %d", Config.RotationTTL)
+				}
+			case envRotationClients:
+				clients, err := validatePositiveInt("rotationMaxClients", envVal)
+				if err == nil {
+					Config.RotationMaxClients = clients
+					log.Debugf("rotationMaxClients set to %d", Config.RotationMaxClients)
+				}
+			case envDailyTimezone:
+				if _, err := validateTimezone(envVal); err == nil {
+					Config.DailyTimezone = envVal
+					log.Debugf("dailyTimezone set to '%s'", Config.DailyTimezone)
+				}
+			case envAdminEnabled:
+				enabled, err := strconv.ParseBool(envVal)
+				if err == nil {
+					Config.AdminEnabled = enabled
+					log.Debugf("adminEnabled set to %v", Config.AdminEnabled)
+				}
+			case envAdminAddress:
+				Config.AdminAddress = envVal
+				log.Debugf("adminAddress set to '%s'", Config.AdminAddress)
+			case envAdminToken:
+				Config.AdminToken = envVal
+				log.Debug("adminToken set")
+			case envMetricsEnabled:
+				enabled, err := strconv.ParseBool(envVal)
+				if err == nil {
+					Config.MetricsEnabled = enabled
+					log.Debugf("metricsEnabled set to %v", Config.MetricsEnabled)
+				}
+			case envMetricsAddress:
+				Config.MetricsAddress = envVal
+				log.Debugf("metricsAddress set to '%s'", Config.MetricsAddress)
+			case envHealthAddress:
+				Config.HealthAddress = envVal
+				log.Debugf("healthAddress set to '%s'", Config.HealthAddress)
+			case envTracingExporter:
+				exporter, err := validateTracingExporter(envVal)
+				if err == nil {
+					Config.TracingExporter = exporter
+					log.Debugf("tracingExporter set to '%s'", Config.TracingExporter)
+				}
+			case envTracingEndpoint:
+				Config.TracingEndpoint = envVal
+				log.Debugf("tracingEndpoint set to '%s'", Config.TracingEndpoint)
+			case envAdaptive:
+				adaptive, err := strconv.ParseBool(envVal)
+				if err == nil {
+					Config.AdaptiveDifficulty = adaptive
+					log.Debugf("adaptiveDifficulty set to %v", Config.AdaptiveDifficulty)
+				}
+			case envMinDifficulty:
+				diff, err := validateDifficulty(envVal)
+				if err == nil {
+					Config.MinDifficulty = diff
+					log.Debugf("minDifficulty set to %d", Config.MinDifficulty)
+				}
+			case envMaxDifficulty:
+				diff, err := validateDifficulty(envVal)
+				if err == nil {
+					Config.MaxDifficulty = diff
+					log.Debugf("maxDifficulty set to %d", Config.MaxDifficulty)
+				}
+			case envDiffInterval:
+				interval, err := validateTimeout(envVal)
+				if err == nil {
+					Config.DifficultyInterval = interval
+					log.Debugf("difficultyInterval set to %d", Config.DifficultyInterval)
+				}
+			case envDiffSmoothing:
+				smoothing, err := validateSmoothing(envVal)
+				if err == nil {
+					Config.DifficultySmoothing = smoothing
+					log.Debugf("difficultySmoothing set to %v", Config.DifficultySmoothing)
+				}
+			case envLoadMaxConns:
+				conns, err := validateNonNegativeInt("loadMaxConnections", envVal)
+				if err == nil {
+					Config.LoadMaxConnections = conns
+					log.Debugf("loadMaxConnections set to %d", Config.LoadMaxConnections)
+				}
+			case envLoadMaxRate:
+				rate, err := validateLoadLimit(envVal)
+				if err == nil {
+					Config.LoadMaxAcceptRate = rate
+					log.Debugf("loadMaxAcceptRate set to %v", Config.LoadMaxAcceptRate)
+				}
+			case envLoadMaxCPU:
+				cpu, err := validateLoadLimit(envVal)
+				if err == nil {
+					Config.LoadMaxCPU = cpu
+					log.Debugf("loadMaxCPU set to %v", Config.LoadMaxCPU)
+				}
+			case envReputation:
+				enabled, err := strconv.ParseBool(envVal)
+				if err == nil {
+					Config.ReputationEnabled = enabled
+					log.Debugf("reputationEnabled set to %v", Config.ReputationEnabled)
+				}
+			case envRepHalfLife:
+				halfLife, err := validateTimeout(envVal)
+				if err == nil {
+					Config.ReputationHalfLife = halfLife
+					log.Debugf("reputationHalfLife set to %d", Config.ReputationHalfLife)
+				}
+			case envRepRequest:
+				score, err := validateScore(envVal)
+				if err == nil {
+					Config.ReputationRequestScore = score
+					log.Debugf("reputationRequestScore set to %v", Config.ReputationRequestScore)
+				}
+			case envRepSolved:
+				score, err := validateScore(envVal)
+				if err == nil {
+					Config.ReputationSolvedScore = score
+					log.Debugf("reputationSolvedScore set to %v", Config.ReputationSolvedScore)
+				}
+			case envRepFailed:
+				score, err := validateScore(envVal)
+				if err == nil {
+					Config.ReputationFailedScore = score
+					log.Debugf("reputationFailedScore set to %v", Config.ReputationFailedScore)
+				}
+			case envRepMalformed:
+				score, err := validateScore(envVal)
+				if err == nil {
+					Config.ReputationMalformedScore = score
+					log.Debugf("reputationMalformedScore set to %v", Config.ReputationMalformedScore)
+				}
+			case envRepPointsPerBit:
+				points, err := validateNonNegativeFloat("reputationPointsPerBit", envVal)
+				if err == nil {
+					Config.ReputationPointsPerBit = points
+					log.Debugf("reputationPointsPerBit set to %v", Config.ReputationPointsPerBit)
+				}
+			case envRepMaxPenalty:
+				bits, err := validateDifficulty(envVal)
+				if err == nil {
+					Config.ReputationMaxPenalty = bits
+					log.Debugf("reputationMaxPenalty set to %d", Config.ReputationMaxPenalty)
+				}
+			case envRepMaxBonus:
+				bits, err := validateDifficulty(envVal)
+				if err == nil {
+					Config.ReputationMaxBonus = bits
+					log.Debugf("reputationMaxBonus set to %d", Config.ReputationMaxBonus)
+				}
+			case envRepSubnetWeight:
+				weight, err := validateNonNegativeFloat("reputationSubnetWeight", envVal)
+				if err == nil {
+					Config.ReputationSubnetWeight = weight
+					log.Debugf("reputationSubnetWeight set to %v", Config.ReputationSubnetWeight)
+				}
+			case envRepIPv4Prefix:
+				prefix, err := validatePrefix(envVal, 32)
+				if err == nil {
+					Config.ReputationIPv4Prefix = prefix
+					log.Debugf("reputationIPv4Prefix set to %d", Config.ReputationIPv4Prefix)
+				}
+			case envRepIPv6Prefix:
+				prefix, err := validatePrefix(envVal, 128)
+				if err == nil {
+					Config.ReputationIPv6Prefix = prefix
+					log.Debugf("reputationIPv6Prefix set to %d", Config.ReputationIPv6Prefix)
+				}
+			case envPowAlgorithms:
+				algorithms, err := validateAlgorithms(envVal)
+				if err == nil {
+					Config.PowAlgorithms = algorithms
+					log.Debugf("powAlgorithms set to %v", Config.PowAlgorithms)
+				}
+			case envPowOffsets:
+				offsets, err := validateDifficultyOffsets(envVal)
+				if err == nil {
+					Config.PowDifficultyOffsets = offsets
+					log.Debugf("powDifficultyOffsets set to %v", Config.PowDifficultyOffsets)
+				}
+			case envArgon2Time:
+				cost, err := validateCost(envVal)
+				if err == nil {
+					Config.Argon2Time = cost
+					log.Debugf("argon2Time set to %d", Config.Argon2Time)
+				}
+			case envArgon2Memory:
+				cost, err := validateCost(envVal)
+				if err == nil {
+					Config.Argon2Memory = cost
+					log.Debugf("argon2Memory set to %d", Config.Argon2Memory)
+				}
+			case envArgon2Threads:
+				threads, err := validateThreads(envVal)
+				if err == nil {
+					Config.Argon2Threads = threads
+					log.Debugf("argon2Threads set to %d", Config.Argon2Threads)
+				}
+			case envScryptN:
+				n, err := validateScryptN(envVal)
+				if err == nil {
+					Config.ScryptN = n
+					log.Debugf("scryptN set to %d", Config.ScryptN)
+				}
+			case envScryptR:
+				cost, err := validateCost(envVal)
+				if err == nil {
+					Config.ScryptR = cost
+					log.Debugf("scryptR set to %d", Config.ScryptR)
+				}
+			case envScryptP:
+				cost, err := validateCost(envVal)
+				if err == nil {
+					Config.ScryptP = cost
+					log.Debugf("scryptP set to %d", Config.ScryptP)
+				}
+			case envEthashCache:
+				size, err := validateCost(envVal)
+				if err == nil {
+					Config.EthashCacheSize = size
+					log.Debugf("ethashCacheSize set to %d", Config.EthashCacheSize)
+				}
+			case envEthashDataset:
+				size, err := validateCost(envVal)
+				if err == nil {
+					Config.EthashDatasetSize = size
+					log.Debugf("ethashDatasetSize set to %d", Config.EthashDatasetSize)
+				}
+			case envEthashEpoch:
+				length, err := validateTimeout(envVal)
+				if err == nil {
+					Config.EthashEpochLength = length
+					log.Debugf("ethashEpochLength set to %d", Config.EthashEpochLength)
+				}
+			case envHashcashHash:
+				hash, err := validateHashcashHash(envVal)
+				if err == nil {
+					Config.HashcashHash = hash
+					log.Debugf("hashcashHash set to %s", Config.HashcashHash)
+				}
+			case envHashcashWindow:
+				window, err := validateTimeout(envVal)
+				if err == nil {
+					Config.HashcashWindow = window
+					log.Debugf("hashcashWindow set to %d", Config.HashcashWindow)
+				}
 			}
 		}
 	}
@@ -146,6 +828,7 @@ func validatePort(in string) (int, error) {
 	return num, nil
 }
 
+// validateTimeout parses a duration in milliseconds: a timeout, an interval, a TTL or a window.
 func validateTimeout(in string) (int, error) {
 	num, err := strconv.Atoi(in)
 	if err != nil {
@@ -168,6 +851,229 @@ func validateDifficulty(in string) (int, error) {
 	return num, nil
 }
 
+func validateRequestStore(in string) (string, error) {
+	if in != RequestStoreMemory && in != RequestStoreBolt && in != RequestStoreRedis {
+		return "", errors.New("incorrect request store")
+	}
+	return in, nil
+}
+
+func validateQuoteStorage(in string) (string, error) {
+	if in != QuoteStorageMemory && in != QuoteStorageSQLite {
+		return "", errors.New("incorrect quote storage")
+	}
+	return in, nil
+}
+
+func validateHashcashHash(in string) (string, error) {
+	if in != HashcashSHA1 && in != HashcashSHA256 {
+		return "", errors.New("incorrect hashcash hash")
+	}
+	return in, nil
+}
+
+func validateTracingExporter(in string) (string, error) {
+	if in != TracingExporterNone && in != TracingExporterStdout && in != TracingExporterOTLP {
+		return "", errors.New("incorrect tracing exporter")
+	}
+	return in, nil
+}
+
+func validateReplayDetector(in string) (string, error) {
+	if in != ReplayDetectorStore && in != ReplayDetectorBloom {
+		return "", errors.New("incorrect replay detector")
+	}
+	return in, nil
+}
+
+func validateFalsePositiveRate(in string) (float64, error) {
+	rate, err := strconv.ParseFloat(in, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate <= 0 || rate >= 1 {
+		return 0, errors.New("incorrect false positive rate")
+	}
+	return rate, nil
+}
+
+func validateSmoothing(in string) (float64, error) {
+	smoothing, err := strconv.ParseFloat(in, 64)
+	if err != nil {
+		return 0, err
+	}
+	if smoothing < 0 || smoothing >= 1 {
+		return 0, errors.New("incorrect difficulty smoothing")
+	}
+	return smoothing, nil
+}
+
+func validateLoadLimit(in string) (float64, error) {
+	limit, err := strconv.ParseFloat(in, 64)
+	if err != nil {
+		return 0, err
+	}
+	if limit < 0 || math.IsInf(limit, 0) || math.IsNaN(limit) {
+		return 0, errors.New("incorrect load limit")
+	}
+	return limit, nil
+}
+
+func validateScore(in string) (float64, error) {
+	score, err := strconv.ParseFloat(in, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsInf(score, 0) || math.IsNaN(score) {
+		return 0, errors.New("incorrect score")
+	}
+	return score, nil
+}
+
+func validatePrefix(in string, bits int) (int, error) {
+	num, err := strconv.Atoi(in)
+	if err != nil {
+		return 0, err
+	}
+	if num < 0 || num > bits {
+		return 0, errors.New("incorrect prefix length")
+	}
+	return num, nil
+}
+
+func validateAlgorithm(in string) (string, error) {
+	if in != AlgorithmKeccak && in != AlgorithmArgon2id && in != AlgorithmScrypt && in != AlgorithmEthash && in != AlgorithmHashcash {
+		return "", errors.New("incorrect pow algorithm")
+	}
+	return in, nil
+}
+
+// validateAlgorithms splits a comma-separated list of pow algorithms in preference order.
+func validateAlgorithms(in string) ([]string, error) {
+	var algorithms []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(in, ",") {
+		algorithm, err := validateAlgorithm(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		if seen[algorithm] {
+			return nil, errors.New("repeated pow algorithm")
+		}
+		seen[algorithm] = true
+		algorithms = append(algorithms, algorithm)
+	}
+	return algorithms, nil
+}
+
+// validateDifficultyOffsets parses comma-separated algorithm:bits pairs.
+func validateDifficultyOffsets(in string) (map[string]int, error) {
+	offsets := make(map[string]int)
+	for _, pair := range strings.Split(in, ",") {
+		name, bits, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, errors.New("incorrect difficulty offset")
+		}
+		algorithm, err := validateAlgorithm(name)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := validateDifficulty(bits)
+		if err != nil {
+			return nil, err
+		}
+		offsets[algorithm] = offset
+	}
+	return offsets, nil
+}
+
+func validateCost(in string) (int, error) {
+	num, err := strconv.Atoi(in)
+	if err != nil {
+		return 0, err
+	}
+	if num <= 0 || num > math.MaxInt32 {
+		return 0, errors.New("incorrect cost")
+	}
+	return num, nil
+}
+
+func validateThreads(in string) (int, error) {
+	num, err := strconv.Atoi(in)
+	if err != nil {
+		return 0, err
+	}
+	if num <= 0 || num > math.MaxUint8 {
+		return 0, errors.New("incorrect threads count")
+	}
+	return num, nil
+}
+
+func validateScryptN(in string) (int, error) {
+	num, err := validateCost(in)
+	if err != nil {
+		return 0, err
+	}
+	if num < 2 || num&(num-1) != 0 {
+		return 0, errors.New("scrypt N must be a power of two")
+	}
+	return num, nil
+}
+
+func validateTimezone(in string) (*time.Location, error) {
+	if in == "" {
+		return nil, errors.New("empty timezone")
+	}
+	return time.LoadLocation(in)
+}
+
+func validateShardsCnt(in string) (int, error) {
+	num, err := strconv.Atoi(in)
+	if err != nil {
+		return 0, err
+	}
+	if num <= 0 {
+		return 0, errors.New("incorrect shards count")
+	}
+	return num, nil
+}
+
+// validatePositiveInt parses a count or a limit, name is the setting it is for.
+func validatePositiveInt(name string, in string) (int, error) {
+	num, err := strconv.Atoi(in)
+	if err != nil {
+		return 0, fmt.Errorf("incorrect %s: %w", name, err)
+	}
+	if num <= 0 {
+		return 0, fmt.Errorf("incorrect %s: must be positive", name)
+	}
+	return num, nil
+}
+
+// validateNonNegativeInt parses a count or a limit where 0 means none or disabled.
+func validateNonNegativeInt(name string, in string) (int, error) {
+	num, err := strconv.Atoi(in)
+	if err != nil {
+		return 0, fmt.Errorf("incorrect %s: %w", name, err)
+	}
+	if num < 0 {
+		return 0, fmt.Errorf("incorrect %s: must not be negative", name)
+	}
+	return num, nil
+}
+
+// validateNonNegativeFloat parses a weight or a ratio, name is the setting it is for.
+func validateNonNegativeFloat(name string, in string) (float64, error) {
+	num, err := strconv.ParseFloat(in, 64)
+	if err != nil {
+		return 0, fmt.Errorf("incorrect %s: %w", name, err)
+	}
+	if num < 0 || math.IsInf(num, 0) || math.IsNaN(num) {
+		return 0, fmt.Errorf("incorrect %s: must be a non-negative number", name)
+	}
+	return num, nil
+}
+
 func validateLogLevel(in string) (LogLevel, error) {
 	if _, ok := logLevelsMap[LogLevel(in)]; !ok {
 		return "", errors.New("incorrect log level")
@@ -175,6 +1081,20 @@ func validateLogLevel(in string) (LogLevel, error) {
 	return LogLevel(in), nil
 }
 
+func validateMode(in string) (string, error) {
+	if in != ModeSession && in != ModeLegacy {
+		return "", errors.New("incorrect mode")
+	}
+	return in, nil
+}
+
+func validateChallengeMode(in string) (string, error) {
+	if in != ChallengeModeStored && in != ChallengeModeSigned {
+		return "", errors.New("incorrect challenge mode")
+	}
+	return in, nil
+}
+
 func BuildPort(port int) string {
 	return fmt.Sprintf(":%d", port)
 }
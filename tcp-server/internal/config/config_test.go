@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -239,3 +240,1263 @@ func Test_validateLogLevel(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateMode(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 session",
+			args:    args{in: "session"},
+			want:    ModeSession,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 legacy",
+			args:    args{in: "legacy"},
+			want:    ModeLegacy,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown",
+			args:    args{in: "stateful"},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 capital",
+			args:    args{in: "Session"},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateMode(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateMode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateChallengeMode(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 stored",
+			args:    args{in: "stored"},
+			want:    ChallengeModeStored,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 signed",
+			args:    args{in: "signed"},
+			want:    ChallengeModeSigned,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown",
+			args:    args{in: "stateless"},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 capital",
+			args:    args{in: "Signed"},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateChallengeMode(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateChallengeMode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateChallengeMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateShardsCnt(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "Success #1",
+			args:    args{in: "100"},
+			want:    100,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 single shard",
+			args:    args{in: "1"},
+			want:    1,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 spaces",
+			args:    args{in: "10, "},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 negative",
+			args:    args{in: "-80"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #3 char",
+			args:    args{in: "ten"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #4 zero",
+			args:    args{in: "0"},
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateShardsCnt(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateShardsCnt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateShardsCnt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validatePositiveInt(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		name string
+		in   string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr string
+	}{
+		{
+			name: "Success #1",
+			args: args{name: "rotationMaxClients", in: "100000"},
+			want: 100000,
+		},
+		{
+			name:    "Failed #1 zero",
+			args:    args{name: "rotationMaxClients", in: "0"},
+			wantErr: "incorrect rotationMaxClients: must be positive",
+		},
+		{
+			name:    "Failed #2 negative",
+			args:    args{name: "bloomCapacity", in: "-1"},
+			wantErr: "incorrect bloomCapacity: must be positive",
+		},
+		{
+			name:    "Failed #3 char",
+			args:    args{name: "rotationHistorySize", in: "ten"},
+			wantErr: `incorrect rotationHistorySize: strconv.Atoi: parsing "ten": invalid syntax`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validatePositiveInt(tt.args.name, tt.args.in)
+			if err != nil && err.Error() != tt.wantErr || err == nil && tt.wantErr != "" {
+				t.Errorf("validatePositiveInt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validatePositiveInt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateNonNegativeInt(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		name string
+		in   string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr string
+	}{
+		{
+			name: "Success #1",
+			args: args{name: "loadMaxConnections", in: "1000"},
+			want: 1000,
+		},
+		{
+			name: "Success #2 disabled",
+			args: args{name: "loadMaxConnections", in: "0"},
+			want: 0,
+		},
+		{
+			name:    "Failed #1 negative",
+			args:    args{name: "loadMaxConnections", in: "-1"},
+			wantErr: "incorrect loadMaxConnections: must not be negative",
+		},
+		{
+			name:    "Failed #2 char",
+			args:    args{name: "loadMaxConnections", in: "ten"},
+			wantErr: `incorrect loadMaxConnections: strconv.Atoi: parsing "ten": invalid syntax`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateNonNegativeInt(tt.args.name, tt.args.in)
+			if err != nil && err.Error() != tt.wantErr || err == nil && tt.wantErr != "" {
+				t.Errorf("validateNonNegativeInt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateNonNegativeInt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateNonNegativeFloat(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		name string
+		in   string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    float64
+		wantErr string
+	}{
+		{
+			name: "Success #1",
+			args: args{name: "reputationSubnetWeight", in: "0.25"},
+			want: 0.25,
+		},
+		{
+			name: "Success #2 zero",
+			args: args{name: "reputationPointsPerBit", in: "0"},
+			want: 0,
+		},
+		{
+			name:    "Failed #1 negative",
+			args:    args{name: "reputationSubnetWeight", in: "-0.5"},
+			wantErr: "incorrect reputationSubnetWeight: must be a non-negative number",
+		},
+		{
+			name:    "Failed #2 NaN",
+			args:    args{name: "reputationPointsPerBit", in: "NaN"},
+			wantErr: "incorrect reputationPointsPerBit: must be a non-negative number",
+		},
+		{
+			name:    "Failed #3 char",
+			args:    args{name: "reputationPointsPerBit", in: "five"},
+			wantErr: `incorrect reputationPointsPerBit: strconv.ParseFloat: parsing "five": invalid syntax`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateNonNegativeFloat(tt.args.name, tt.args.in)
+			if err != nil && err.Error() != tt.wantErr || err == nil && tt.wantErr != "" {
+				t.Errorf("validateNonNegativeFloat() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateNonNegativeFloat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateRequestStore(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 memory",
+			args:    args{in: "memory"},
+			want:    RequestStoreMemory,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 bolt",
+			args:    args{in: "bolt"},
+			want:    RequestStoreBolt,
+			wantErr: false,
+		},
+		{
+			name:    "Success #3 redis",
+			args:    args{in: "redis"},
+			want:    RequestStoreRedis,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown",
+			args:    args{in: "etcd"},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 capital",
+			args:    args{in: "Bolt"},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateRequestStore(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRequestStore() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateRequestStore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateQuoteStorage(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 memory",
+			args:    args{in: "memory"},
+			want:    QuoteStorageMemory,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 sqlite",
+			args:    args{in: "sqlite"},
+			want:    QuoteStorageSQLite,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown",
+			args:    args{in: "postgres"},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 capital",
+			args:    args{in: "SQLite"},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateQuoteStorage(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateQuoteStorage() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateQuoteStorage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateTracingExporter(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 none",
+			args:    args{in: "none"},
+			want:    TracingExporterNone,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 stdout",
+			args:    args{in: "stdout"},
+			want:    TracingExporterStdout,
+			wantErr: false,
+		},
+		{
+			name:    "Success #3 otlp",
+			args:    args{in: "otlp"},
+			want:    TracingExporterOTLP,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown",
+			args:    args{in: "jaeger"},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 empty",
+			args:    args{in: ""},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateTracingExporter(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateTracingExporter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateTracingExporter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateHashcashHash(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 sha1",
+			args:    args{in: "sha1"},
+			want:    HashcashSHA1,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 sha256",
+			args:    args{in: "sha256"},
+			want:    HashcashSHA256,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown",
+			args:    args{in: "md5"},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 empty",
+			args:    args{in: ""},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateHashcashHash(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateHashcashHash() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateHashcashHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateReplayDetector(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 store",
+			args:    args{in: "store"},
+			want:    ReplayDetectorStore,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 bloom",
+			args:    args{in: "bloom"},
+			want:    ReplayDetectorBloom,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown",
+			args:    args{in: "cuckoo"},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 capital",
+			args:    args{in: "Bloom"},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateReplayDetector(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateReplayDetector() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateReplayDetector() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateTimezone(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 UTC",
+			args:    args{in: "UTC"},
+			want:    "UTC",
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 location",
+			args:    args{in: "Europe/Moscow"},
+			want:    "Europe/Moscow",
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 empty",
+			args:    args{in: ""},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 unknown",
+			args:    args{in: "Mars/Olympus"},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateTimezone(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateTimezone() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != nil && got.String() != tt.want {
+				t.Errorf("validateTimezone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateFalsePositiveRate(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    float64
+		wantErr bool
+	}{
+		{
+			name:    "Success #1",
+			args:    args{in: "0.001"},
+			want:    0.001,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 exponent",
+			args:    args{in: "1e-6"},
+			want:    0.000001,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 zero",
+			args:    args{in: "0"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 one",
+			args:    args{in: "1"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #3 negative",
+			args:    args{in: "-0.1"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #4 char",
+			args:    args{in: "one percent"},
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateFalsePositiveRate(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFalsePositiveRate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateFalsePositiveRate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateSmoothing(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    float64
+		wantErr bool
+	}{
+		{
+			name:    "Success #1",
+			args:    args{in: "0.8"},
+			want:    0.8,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 zero",
+			args:    args{in: "0"},
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 one",
+			args:    args{in: "1"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 negative",
+			args:    args{in: "-0.5"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #3 char",
+			args:    args{in: "high"},
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateSmoothing(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSmoothing() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateSmoothing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateLoadLimit(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    float64
+		wantErr bool
+	}{
+		{
+			name:    "Success #1",
+			args:    args{in: "250"},
+			want:    250,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 fraction",
+			args:    args{in: "0.75"},
+			want:    0.75,
+			wantErr: false,
+		},
+		{
+			name:    "Success #3 disabled",
+			args:    args{in: "0"},
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 negative",
+			args:    args{in: "-1"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 infinity",
+			args:    args{in: "Inf"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #3 char",
+			args:    args{in: "many"},
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateLoadLimit(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateLoadLimit() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateLoadLimit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateScore(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    float64
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 positive",
+			args:    args{in: "5"},
+			want:    5,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 negative",
+			args:    args{in: "-1.5"},
+			want:    -1.5,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 NaN",
+			args:    args{in: "NaN"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 char",
+			args:    args{in: "bad"},
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateScore(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateScore() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateScore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validatePrefix(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in   string
+		bits int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 IPv4",
+			args:    args{in: "24", bits: 32},
+			want:    24,
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 IPv6",
+			args:    args{in: "64", bits: 128},
+			want:    64,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 too long for IPv4",
+			args:    args{in: "64", bits: 32},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 negative",
+			args:    args{in: "-8", bits: 32},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #3 char",
+			args:    args{in: "/24", bits: 32},
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validatePrefix(tt.args.in, tt.args.bits)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePrefix() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validatePrefix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateAlgorithms(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "Success #1 single",
+			args:    args{in: "keccak"},
+			want:    []string{AlgorithmKeccak},
+			wantErr: false,
+		},
+		{
+			name:    "Success #2 preference order",
+			args:    args{in: "ethash, argon2id,scrypt,keccak"},
+			want:    []string{AlgorithmEthash, AlgorithmArgon2id, AlgorithmScrypt, AlgorithmKeccak},
+			wantErr: false,
+		},
+		{
+			name:    "Success #3 hashcash",
+			args:    args{in: "hashcash,keccak"},
+			want:    []string{AlgorithmHashcash, AlgorithmKeccak},
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown",
+			args:    args{in: "keccak,sha256"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 repeated",
+			args:    args{in: "keccak,keccak"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateAlgorithms(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAlgorithms() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateAlgorithms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateDifficultyOffsets(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]int
+		wantErr bool
+	}{
+		{
+			name:    "Success #1",
+			args:    args{in: "argon2id:16, scrypt:15,ethash:6"},
+			want:    map[string]int{AlgorithmArgon2id: 16, AlgorithmScrypt: 15, AlgorithmEthash: 6},
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 unknown algorithm",
+			args:    args{in: "md5:3"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 no offset",
+			args:    args{in: "argon2id"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #3 offset out of range",
+			args:    args{in: "argon2id:300"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateDifficultyOffsets(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDifficultyOffsets() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateDifficultyOffsets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateCost(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "Success #1",
+			args:    args{in: "65536"},
+			want:    65536,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 zero",
+			args:    args{in: "0"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 negative",
+			args:    args{in: "-1"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #3 char",
+			args:    args{in: "1k"},
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateCost(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCost() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateThreads(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "Success #1",
+			args:    args{in: "4"},
+			want:    4,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 zero",
+			args:    args{in: "0"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 too many",
+			args:    args{in: "256"},
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateThreads(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateThreads() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateThreads() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateScryptN(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "Success #1",
+			args:    args{in: "16384"},
+			want:    16384,
+			wantErr: false,
+		},
+		{
+			name:    "Failed #1 not a power of two",
+			args:    args{in: "10000"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 one",
+			args:    args{in: "1"},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Failed #3 char",
+			args:    args{in: "N"},
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateScryptN(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateScryptN() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("validateScryptN() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// validConfiguration passes validate, tests break one setting at a time.
+func validConfiguration() Configuration {
+	return Configuration{
+		Mode:                   ModeSession,
+		ChallengeMode:          ChallengeModeSigned,
+		ReplayDetector:         ReplayDetectorBloom,
+		ChallengeTTL:           60000,
+		SolvedRetention:        60000,
+		QuoteRotation:          true,
+		RotationTTL:            3600000,
+		BloomCapacity:          1000,
+		BloomFalsePositiveRate: 0.001,
+	}
+}
+
+func TestConfiguration_validate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		modify  func(c *Configuration)
+		wantErr bool
+	}{
+		{
+			name:   "Success #1",
+			modify: func(c *Configuration) {},
+		},
+		{
+			name: "Success #2 bloom settings unused",
+			modify: func(c *Configuration) {
+				c.ReplayDetector = ReplayDetectorStore
+				c.BloomCapacity = 0
+				c.BloomFalsePositiveRate = 0
+			},
+		},
+		{
+			name:    "Failed #1 mode typo",
+			modify:  func(c *Configuration) { c.Mode = "sesion" },
+			wantErr: true,
+		},
+		{
+			name:    "Failed #2 challenge mode typo",
+			modify:  func(c *Configuration) { c.ChallengeMode = "sigend" },
+			wantErr: true,
+		},
+		{
+			name:    "Failed #3 replay detector typo",
+			modify:  func(c *Configuration) { c.ReplayDetector = "blom" },
+			wantErr: true,
+		},
+		{
+			name:    "Failed #4 challenge TTL zero",
+			modify:  func(c *Configuration) { c.ChallengeTTL = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "Failed #5 solved retention missing",
+			modify:  func(c *Configuration) { c.SolvedRetention = 0 },
+			wantErr: true,
+		},
+		{
+			name: "Success #3 rotation disabled",
+			modify: func(c *Configuration) {
+				c.QuoteRotation = false
+				c.RotationTTL = 0
+			},
+		},
+		{
+			name:    "Failed #6 rotation TTL zero",
+			modify:  func(c *Configuration) { c.RotationTTL = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "Failed #7 bloom with stored challenges",
+			modify:  func(c *Configuration) { c.ChallengeMode = ChallengeModeStored },
+			wantErr: true,
+		},
+		{
+			name:    "Failed #8 bloom capacity zero",
+			modify:  func(c *Configuration) { c.BloomCapacity = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "Failed #9 false positive rate zero",
+			modify:  func(c *Configuration) { c.BloomFalsePositiveRate = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "Failed #10 false positive rate one",
+			modify:  func(c *Configuration) { c.BloomFalsePositiveRate = 1 },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfiguration()
+			tt.modify(&c)
+			if err := c.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Configuration.validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestServer_Handler(t *testing.T) {
+	var ready atomic.Bool
+	srv := httptest.NewServer(New("", ready.Load).Handler())
+	defer srv.Close()
+	ctx := context.Background()
+
+	if err := Check(ctx, srv.URL+LivenessPath); err != nil {
+		t.Errorf("Check(%s) error = %v", LivenessPath, err)
+	}
+	if err := Check(ctx, srv.URL+ReadinessPath); err == nil {
+		t.Errorf("Check(%s) succeeded before the server is ready", ReadinessPath)
+	}
+
+	ready.Store(true)
+	if err := Check(ctx, srv.URL+ReadinessPath); err != nil {
+		t.Errorf("Check(%s) error = %v", ReadinessPath, err)
+	}
+}
+
+func TestURL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{
+			name:    "Any interface",
+			address: ":8083",
+			want:    "http://127.0.0.1:8083/readyz",
+		},
+		{
+			name:    "Unspecified IPv4",
+			address: "0.0.0.0:8083",
+			want:    "http://127.0.0.1:8083/readyz",
+		},
+		{
+			name:    "Host",
+			address: "tcp_server:8083",
+			want:    "http://tcp_server:8083/readyz",
+		},
+		{
+			name:    "IPv6",
+			address: "[::1]:8083",
+			want:    "http://[::1]:8083/readyz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := URL(tt.address, ReadinessPath); got != tt.want {
+				t.Errorf("URL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
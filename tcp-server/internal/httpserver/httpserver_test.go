@@ -0,0 +1,51 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		addr    string
+		cancel  bool
+		wantErr bool
+	}{
+		{
+			name:   "Shut down with the context",
+			addr:   "127.0.0.1:0",
+			cancel: true,
+		},
+		{
+			name:    "Invalid address",
+			addr:    "127.0.0.1:-1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan error)
+			go func() { done <- Run(ctx, tt.addr, http.NotFoundHandler()) }()
+			if tt.cancel {
+				cancel()
+			}
+
+			select {
+			case err := <-done:
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Run() didn't return")
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/httpserver"
+)
+
+const namespace = "wow"
+
+// Reasons a solution is rejected for.
+const (
+	ReasonBadParse       = "bad_parse"
+	ReasonInvalidPOW     = "invalid_pow"
+	ReasonDoubleWork     = "double_work"
+	ReasonUnknownRequest = "unknown_request"
+	ReasonExpired        = "expired"
+	ReasonStoreError     = "store_error"
+)
+
+// Results of a quotes reload.
+const (
+	ReloadOK        = "ok"
+	ReloadError     = "error"
+	ReloadUnchanged = "unchanged"
+)
+
+var (
+	ConnectionsAccepted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "connections_accepted_total",
+		Help:      "Connections accepted by the tcp-server.",
+	})
+	ConnectionsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "connections_active",
+		Help:      "Connections being served.",
+	})
+	ChallengesIssued = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "challenges_issued_total",
+		Help:      "Challenges sent to clients.",
+	})
+	SolutionsAccepted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "solutions_accepted_total",
+		Help:      "Solutions that passed verification.",
+	})
+	SolutionsRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "solutions_rejected_total",
+		Help:      "Solutions rejected, by reason.",
+	}, []string{"reason"})
+	POWVerification = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pow_verification_seconds",
+		Help:      "Time spent verifying a proof of work.",
+		Buckets:   prometheus.ExponentialBuckets(0.000001, 4, 10),
+	})
+	Difficulty = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "difficulty",
+		Help:      "Difficulty of the last issued challenge.",
+	})
+	QuotesDelivered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quotes_delivered_total",
+		Help:      "Quotes sent to clients, by kind: random or daily.",
+	}, []string{"kind"})
+	// RequestStoreEntries is only reported by the memory store: counting the bolt and redis
+	// stores means reading every entry, so they report counts via GET /state instead.
+	RequestStoreEntries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "request_store_entries",
+		Help:      "Requests kept by the in-memory request store, by shard.",
+	}, []string{"shard"})
+	QuotesReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quotes_reloads_total",
+		Help:      "Reloads of the quotes file, by result: ok, error or unchanged.",
+	}, []string{"result"})
+)
+
+// ReplayFilter exports the fill ratio of the current Bloom replay filter and the
+// estimated false positive rate of a replay check. stats is called on every scrape.
+func ReplayFilter(stats func() (fillRatio float64, falsePositiveRate float64)) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "replay_filter_fill_ratio",
+		Help:      "Share of set bits in the current Bloom replay filter.",
+	}, func() float64 {
+		fillRatio, _ := stats()
+		return fillRatio
+	})
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "replay_filter_false_positive_rate",
+		Help:      "Estimated probability that a first solution is rejected as a replay.",
+	}, func() float64 {
+		_, falsePositiveRate := stats()
+		return falsePositiveRate
+	})
+}
+
+// Handler serves /metrics from the default registry.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
+
+// Run serves /metrics on address until ctx is done.
+func Run(ctx context.Context, address string) error {
+	config.Logger.Infof("Launching metrics endpoint on %s...", address)
+	return httpserver.Run(ctx, address, Handler())
+}
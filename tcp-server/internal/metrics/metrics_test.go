@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("GET /metrics body error = %v", err)
+	}
+	return string(body)
+}
+
+func TestHandler(t *testing.T) {
+	ConnectionsAccepted.Inc()
+	SolutionsRejected.WithLabelValues(ReasonExpired).Inc()
+	RequestStoreEntries.WithLabelValues("3").Set(2)
+	QuotesReloads.WithLabelValues(ReloadUnchanged).Inc()
+	Difficulty.Set(12)
+
+	body := scrape(t)
+
+	tests := []string{
+		"wow_connections_accepted_total 1",
+		`wow_solutions_rejected_total{reason="expired"} 1`,
+		`wow_request_store_entries{shard="3"} 2`,
+		`wow_quotes_reloads_total{result="unchanged"} 1`,
+		"wow_difficulty 12",
+		"# TYPE wow_pow_verification_seconds histogram",
+	}
+	for _, want := range tests {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET /metrics doesn't contain %q", want)
+		}
+	}
+}
+
+func TestReplayFilter(t *testing.T) {
+	ReplayFilter(func() (float64, float64) {
+		return 0.25, 0.001
+	})
+
+	body := scrape(t)
+
+	for _, want := range []string{"wow_replay_filter_fill_ratio 0.25", "wow_replay_filter_false_positive_rate 0.001"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET /metrics doesn't contain %q", want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	config.InitLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- Run(ctx, "127.0.0.1:0")
+	}()
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
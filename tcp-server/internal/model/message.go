@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+
+	"github.com/pullya/wow_tcp_server/shared/pow"
 )
 
 const (
@@ -12,6 +14,23 @@ const (
 	MessageTypeWow       = "wow"
 	MessageTypeSolution  = "solution"
 	MessageTypeRequest   = "request"
+	MessageTypeError     = "error"
+	MessageTypeDaily     = "daily"
+)
+
+// Proof of work algorithms, a challenge without one is keccak.
+const (
+	AlgorithmKeccak   = pow.AlgorithmKeccak
+	AlgorithmArgon2id = pow.AlgorithmArgon2id
+	AlgorithmScrypt   = pow.AlgorithmScrypt
+	AlgorithmEthash   = pow.AlgorithmEthash
+	AlgorithmHashcash = pow.AlgorithmHashcash
+)
+
+// Hash functions of Hashcash stamps.
+const (
+	HashSHA1   = pow.HashSHA1
+	HashSHA256 = pow.HashSHA256
 )
 
 var (
@@ -20,16 +39,29 @@ var (
 		MessageTypeWow:       true,
 		MessageTypeSolution:  true,
 		MessageTypeRequest:   true,
+		MessageTypeError:     true,
+		MessageTypeDaily:     true,
 	}
 )
 
 type Message struct {
-	RequestID     string `json:"request_id"`
-	MessageType   string `json:"message_type"`
-	MessageString string `json:"message_string"`
-	Difficulty    int    `json:"difficulty"`
+	RequestID     string            `json:"request_id"`
+	MessageType   string            `json:"message_type"`
+	MessageString string            `json:"message_string"`
+	Difficulty    int               `json:"difficulty"`
+	Quote         *Quote            `json:"quote,omitempty"`
+	Filter        *QuoteFilter      `json:"filter,omitempty"`
+	ClientID      string            `json:"client_id,omitempty"`
+	Trace         map[string]string `json:"trace,omitempty"`
+	Algorithm     string            `json:"algorithm,omitempty"`
+	Params        *PowParams        `json:"params,omitempty"`
+	// Algorithms are the pow algorithms a client supports, sent with its request.
+	Algorithms []string `json:"algorithms,omitempty"`
 }
 
+// PowParams are the cost parameters of the challenge algorithm, see pow.Params.
+type PowParams = pow.Params
+
 func PrepareMessage(rid string, mType string, mString string, d int) Message {
 	return Message{
 		RequestID:     rid,
@@ -39,6 +71,14 @@ func PrepareMessage(rid string, mType string, mString string, d int) Message {
 	}
 }
 
+// PrepareQuoteMessage builds a wow message carrying the structured quote,
+// with the bare text kept in MessageString for older clients.
+func PrepareQuoteMessage(rid string, q Quote) Message {
+	m := PrepareMessage(rid, MessageTypeWow, q.Text, 0)
+	m.Quote = &q
+	return m
+}
+
 func (m Message) AsJsonString() []byte {
 	result, err := json.Marshal(m)
 	if err != nil {
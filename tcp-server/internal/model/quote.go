@@ -0,0 +1,60 @@
+package model
+
+import "strings"
+
+type Quote struct {
+	ID       string   `json:"id" yaml:"id"`
+	Text     string   `json:"text" yaml:"text"`
+	Author   string   `json:"author,omitempty" yaml:"author"`
+	Source   string   `json:"source,omitempty" yaml:"source"`
+	Language string   `json:"language,omitempty" yaml:"language"`
+	Tags     []string `json:"tags,omitempty" yaml:"tags"`
+}
+
+// QuoteFilter narrows the quote lookup. Empty fields match any quote,
+// a quote must carry every requested tag. Comparison is case-insensitive.
+type QuoteFilter struct {
+	Tags     []string `json:"tags,omitempty"`
+	Author   string   `json:"author,omitempty"`
+	Language string   `json:"language,omitempty"`
+}
+
+// QuoteRequest is what the client asked for, remembered with the issued challenge.
+// ClientID is only kept by signed challenges, where the client can't change it.
+type QuoteRequest struct {
+	QuoteFilter
+	Daily    bool   `json:"daily,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+func (r QuoteRequest) IsEmpty() bool {
+	return !r.Daily && r.QuoteFilter.IsEmpty()
+}
+
+func (f QuoteFilter) IsEmpty() bool {
+	return len(f.Tags) == 0 && f.Author == "" && f.Language == ""
+}
+
+func (f QuoteFilter) Match(q Quote) bool {
+	if f.Author != "" && !strings.EqualFold(f.Author, q.Author) {
+		return false
+	}
+	if f.Language != "" && !strings.EqualFold(f.Language, q.Language) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !hasTag(q.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
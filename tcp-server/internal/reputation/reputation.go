@@ -0,0 +1,213 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+var ErrInvalidAddress = errors.New("invalid address")
+
+// Event is something a source did that changes its score.
+type Event int
+
+const (
+	// EventRequest is a challenge request, so a high request rate adds up.
+	EventRequest Event = iota
+	// EventSolved is an accepted solution.
+	EventSolved
+	// EventFailed is a rejected solution: invalid, repeated or for an unknown challenge.
+	EventFailed
+	// EventMalformed is a message that couldn't be parsed.
+	EventMalformed
+)
+
+// Policy is how events change the score of a source and how the score changes
+// the difficulty. Positive points make a source suspicious, negative ones trusted.
+type Policy struct {
+	Request   float64
+	Solved    float64
+	Failed    float64
+	Malformed float64
+
+	// HalfLife is the time after which a score is halved, zero keeps scores forever.
+	HalfLife time.Duration
+	// PointsPerBit is the score that adds one bit of difficulty.
+	PointsPerBit float64
+	// MaxPenalty and MaxBonus bound the bits added to suspicious and removed from trusted sources.
+	MaxPenalty int
+	MaxBonus   int
+
+	// SubnetWeight is the share of the subnet score added to the score of an address.
+	SubnetWeight float64
+	IPv4Prefix   int
+	IPv6Prefix   int
+}
+
+func (p Policy) points(e Event) float64 {
+	switch e {
+	case EventRequest:
+		return p.Request
+	case EventSolved:
+		return p.Solved
+	case EventFailed:
+		return p.Failed
+	case EventMalformed:
+		return p.Malformed
+	default:
+		return 0
+	}
+}
+
+// Score is the current reputation of an address.
+type Score struct {
+	Address      string  `json:"address"`
+	Subnet       string  `json:"subnet"`
+	AddressScore float64 `json:"address_score"`
+	SubnetScore  float64 `json:"subnet_score"`
+	Score        float64 `json:"score"`
+	Adjustment   int     `json:"adjustment"`
+}
+
+// entry is a decaying score, valid as of updated.
+type entry struct {
+	score   float64
+	updated time.Time
+}
+
+// Tracker keeps the scores of addresses and their subnets.
+type Tracker struct {
+	policy Policy
+
+	mu      sync.Mutex
+	sources map[string]*entry
+	now     func() time.Time
+}
+
+func New(policy Policy) *Tracker {
+	return &Tracker{
+		policy:  policy,
+		sources: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+// Record adds the points of the event to the address and its subnet.
+// Addresses that aren't IPs are ignored.
+func (t *Tracker) Record(address string, e Event) {
+	addr, subnet, err := t.keys(address)
+	if err != nil {
+		return
+	}
+	points := t.policy.points(e)
+	if points == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for _, key := range []string{addr, subnet} {
+		ent, ok := t.sources[key]
+		if !ok {
+			ent = &entry{}
+			t.sources[key] = ent
+		}
+		ent.score = t.decayed(ent, now) + points
+		ent.updated = now
+	}
+}
+
+// Adjust returns difficulty changed by the reputation of the address.
+func (t *Tracker) Adjust(address string, difficulty int) int {
+	score, err := t.Score(address)
+	if err != nil {
+		return difficulty
+	}
+	return min(max(difficulty+score.Adjustment, 0), 256)
+}
+
+// Score returns the current reputation of the address.
+func (t *Tracker) Score(address string) (Score, error) {
+	addr, subnet, err := t.keys(address)
+	if err != nil {
+		return Score{}, err
+	}
+
+	t.mu.Lock()
+	now := t.now()
+	s := Score{
+		Address:      addr,
+		Subnet:       subnet,
+		AddressScore: t.decayed(t.sources[addr], now),
+		SubnetScore:  t.decayed(t.sources[subnet], now),
+	}
+	t.mu.Unlock()
+
+	s.Score = s.AddressScore + t.policy.SubnetWeight*s.SubnetScore
+	if t.policy.PointsPerBit > 0 {
+		bits := int(math.Round(s.Score / t.policy.PointsPerBit))
+		s.Adjustment = min(max(bits, -t.policy.MaxBonus), t.policy.MaxPenalty)
+	}
+	return s, nil
+}
+
+// RunSweeper forgets sources whose score decayed to almost zero every interval until ctx is done.
+func (t *Tracker) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.sweep()
+		}
+	}
+}
+
+func (t *Tracker) sweep() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for key, ent := range t.sources {
+		if math.Abs(t.decayed(ent, now)) < 0.01 {
+			delete(t.sources, key)
+		}
+	}
+}
+
+func (t *Tracker) decayed(ent *entry, now time.Time) float64 {
+	if ent == nil {
+		return 0
+	}
+	if t.policy.HalfLife <= 0 {
+		return ent.score
+	}
+	return ent.score * math.Exp2(-now.Sub(ent.updated).Seconds()/t.policy.HalfLife.Seconds())
+}
+
+// keys are the address and its subnet, both in canonical form.
+func (t *Tracker) keys(address string) (string, string, error) {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return "", "", ErrInvalidAddress
+	}
+	addr = addr.Unmap()
+
+	bits := t.policy.IPv6Prefix
+	if addr.Is4() {
+		bits = t.policy.IPv4Prefix
+	}
+	subnet, err := addr.WithZone("").Prefix(bits)
+	if err != nil {
+		return "", "", ErrInvalidAddress
+	}
+	return addr.String(), subnet.String(), nil
+}
@@ -0,0 +1,154 @@
+package reputation
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	Request:      1,
+	Solved:       -1.5,
+	Failed:       5,
+	Malformed:    10,
+	HalfLife:     10 * time.Minute,
+	PointsPerBit: 5,
+	MaxPenalty:   8,
+	MaxBonus:     2,
+	SubnetWeight: 0.5,
+	IPv4Prefix:   24,
+	IPv6Prefix:   64,
+}
+
+func newTestTracker(now *time.Time) *Tracker {
+	t := New(testPolicy)
+	t.now = func() time.Time { return *now }
+	return t
+}
+
+func TestTracker_Adjust(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		address string
+		events  []Event
+		want    int
+	}{
+		{
+			name:    "Unknown source",
+			address: "10.0.0.1",
+			want:    20,
+		},
+		{
+			name:    "Honest client",
+			address: "10.0.0.1",
+			events:  repeat(20, EventRequest, EventSolved),
+			want:    18,
+		},
+		{
+			name:    "Failed solutions",
+			address: "10.0.0.1",
+			events:  repeat(3, EventRequest, EventFailed),
+			want:    25,
+		},
+		{
+			name:    "Flood of requests",
+			address: "10.0.0.1",
+			events:  repeat(500, EventRequest),
+			want:    28,
+		},
+		{
+			name:    "Malformed messages",
+			address: "2001:db8::1",
+			events:  repeat(2, EventMalformed),
+			want:    26,
+		},
+		{
+			name:    "Not an IP",
+			address: "pipe",
+			events:  repeat(10, EventMalformed),
+			want:    20,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			tracker := newTestTracker(&now)
+			for _, e := range tt.events {
+				tracker.Record(tt.address, e)
+			}
+			if got := tracker.Adjust(tt.address, 20); got != tt.want {
+				t.Errorf("Tracker.Adjust() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTracker_Score(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(&now)
+	tracker.Record("192.0.2.10", EventFailed)
+	tracker.Record("192.0.2.10", EventFailed)
+
+	got, err := tracker.Score("192.0.2.10")
+	if err != nil {
+		t.Fatalf("Tracker.Score() error = %v", err)
+	}
+	want := Score{Address: "192.0.2.10", Subnet: "192.0.2.0/24", AddressScore: 10, SubnetScore: 10, Score: 15, Adjustment: 3}
+	if got != want {
+		t.Errorf("Tracker.Score() = %+v, want %+v", got, want)
+	}
+
+	// a neighbour inherits a share of the subnet score
+	neighbour, _ := tracker.Score("192.0.2.99")
+	if neighbour.AddressScore != 0 || neighbour.Score != 5 {
+		t.Errorf("Tracker.Score() of neighbour = %+v, want score 5", neighbour)
+	}
+	other, _ := tracker.Score("198.51.100.1")
+	if other.Score != 0 {
+		t.Errorf("Tracker.Score() of another subnet = %+v, want score 0", other)
+	}
+
+	// scores halve every half-life
+	now = now.Add(testPolicy.HalfLife)
+	got, _ = tracker.Score("192.0.2.10")
+	if math.Abs(got.AddressScore-5) > 1e-9 || got.Adjustment != 2 {
+		t.Errorf("Tracker.Score() after half-life = %+v, want address score 5", got)
+	}
+
+	if _, err := tracker.Score("not an ip"); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("Tracker.Score() error = %v, want %v", err, ErrInvalidAddress)
+	}
+}
+
+func TestTracker_sweep(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(&now)
+	tracker.Record("10.0.0.1", EventFailed)
+
+	now = now.Add(time.Minute)
+	tracker.sweep()
+	if len(tracker.sources) != 2 {
+		t.Fatalf("sources after a minute = %d, want 2", len(tracker.sources))
+	}
+
+	now = now.Add(24 * time.Hour)
+	tracker.sweep()
+	if len(tracker.sources) != 0 {
+		t.Errorf("sources after a day = %d, want 0", len(tracker.sources))
+	}
+}
+
+func repeat(n int, events ...Event) []Event {
+	var out []Event
+	for i := 0; i < n; i++ {
+		out = append(out, events...)
+	}
+	return out
+}
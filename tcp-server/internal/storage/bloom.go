@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/spaolacci/murmur3"
+)
+
+type bloomFilter struct {
+	bits    []uint64
+	setBits uint64
+	items   uint64
+}
+
+// BloomReplayDetector remembers solved requests in two rotating time-windowed
+// Bloom filters instead of a map. A request is kept for at least one window and
+// at most two, so the window must not be shorter than the challenge TTL.
+// A false positive rejects a genuine first solution as a replay.
+type BloomReplayDetector struct {
+	mu          sync.Mutex
+	current     *bloomFilter
+	previous    *bloomFilter
+	windowStart time.Time
+	window      time.Duration
+	size        uint64
+	hashes      uint64
+	now         func() time.Time
+}
+
+type BloomStats struct {
+	Window                time.Duration
+	Items                 uint64
+	FillRatio             float64
+	EstimatedFalsePosRate float64
+}
+
+// NewBloomReplayDetector sizes each filter for capacity requests per window
+// at the target false positive rate.
+func NewBloomReplayDetector(capacity int, falsePositiveRate float64, window time.Duration) *BloomReplayDetector {
+	size, hashes := bloomParams(capacity, falsePositiveRate)
+
+	bd := &BloomReplayDetector{
+		window: window,
+		size:   size,
+		hashes: hashes,
+		now:    time.Now,
+	}
+	bd.current = bd.newFilter()
+	bd.previous = bd.newFilter()
+	bd.windowStart = bd.now()
+
+	return bd
+}
+
+func (bd *BloomReplayDetector) AddSolved(_ context.Context, request string) error {
+	h1, h2 := murmur3.Sum128([]byte(request))
+
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.rotate()
+
+	if bd.contains(bd.current, h1, h2) || bd.contains(bd.previous, h1, h2) {
+		return ErrAlreadySolved
+	}
+	bd.add(bd.current, h1, h2)
+
+	return nil
+}
+
+// Stats describes the current filter, rotating it first if its window has passed.
+func (bd *BloomReplayDetector) Stats() BloomStats {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.rotate()
+
+	return bd.stats()
+}
+
+func (bd *BloomReplayDetector) stats() BloomStats {
+	fill := float64(bd.current.setBits) / float64(bd.size)
+	prevFill := float64(bd.previous.setBits) / float64(bd.size)
+	k := float64(bd.hashes)
+
+	return BloomStats{
+		Window:    bd.window,
+		Items:     bd.current.items,
+		FillRatio: fill,
+		// a replay check fails if either filter reports a match
+		EstimatedFalsePosRate: 1 - (1-math.Pow(fill, k))*(1-math.Pow(prevFill, k)),
+	}
+}
+
+func (bd *BloomReplayDetector) rotate() {
+	elapsed := bd.now().Sub(bd.windowStart)
+	if elapsed < bd.window {
+		return
+	}
+
+	stats := bd.stats()
+	config.Logger.Debugf("Rotating replay filter: %d items, fill ratio %.4f, estimated false positive rate %.6f",
+		stats.Items, stats.FillRatio, stats.EstimatedFalsePosRate)
+
+	if elapsed >= 2*bd.window {
+		bd.previous = bd.newFilter()
+	} else {
+		bd.previous = bd.current
+	}
+	bd.current = bd.newFilter()
+	bd.windowStart = bd.windowStart.Add(elapsed.Truncate(bd.window))
+}
+
+func (bd *BloomReplayDetector) newFilter() *bloomFilter {
+	return &bloomFilter{bits: make([]uint64, (bd.size+63)/64)}
+}
+
+func (bd *BloomReplayDetector) add(f *bloomFilter, h1, h2 uint64) {
+	for i := uint64(0); i < bd.hashes; i++ {
+		idx := (h1 + i*h2) % bd.size
+		word, mask := idx/64, uint64(1)<<(idx%64)
+		if f.bits[word]&mask == 0 {
+			f.bits[word] |= mask
+			f.setBits++
+		}
+	}
+	f.items++
+}
+
+func (bd *BloomReplayDetector) contains(f *bloomFilter, h1, h2 uint64) bool {
+	for i := uint64(0); i < bd.hashes; i++ {
+		idx := (h1 + i*h2) % bd.size
+		if f.bits[idx/64]&(uint64(1)<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomParams returns the optimal filter size in bits and number of hash functions.
+func bloomParams(capacity int, falsePositiveRate float64) (uint64, uint64) {
+	n := math.Max(float64(capacity), 1)
+	m := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(math.Round(m/n*math.Ln2), 1)
+
+	return uint64(m), uint64(k)
+}
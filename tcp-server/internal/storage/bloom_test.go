@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"testing"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/spaolacci/murmur3"
+)
+
+func (f *bloomFilter) popCount() uint64 {
+	cnt := 0
+	for _, word := range f.bits {
+		cnt += bits.OnesCount64(word)
+	}
+	return uint64(cnt)
+}
+
+func TestBloomReplayDetector_FalsePositiveRate(t *testing.T) {
+	t.Parallel()
+
+	const (
+		capacity = 10000
+		probes   = 100000
+	)
+
+	tests := []struct {
+		name   string
+		target float64
+	}{
+		{
+			name:   "1%",
+			target: 0.01,
+		},
+		{
+			name:   "0.1%",
+			target: 0.001,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bd := NewBloomReplayDetector(capacity, tt.target, time.Hour)
+			for i := 0; i < capacity; i++ {
+				if err := bd.AddSolved(context.Background(), fmt.Sprintf("solved-%d", i)); err != nil && !errors.Is(err, ErrAlreadySolved) {
+					t.Fatalf("BloomReplayDetector.AddSolved() error = %v", err)
+				}
+			}
+
+			falsePositives := 0
+			for i := 0; i < probes; i++ {
+				h1, h2 := murmur3.Sum128([]byte(fmt.Sprintf("fresh-%d", i)))
+				if bd.contains(bd.current, h1, h2) {
+					falsePositives++
+				}
+			}
+			measured := float64(falsePositives) / probes
+			stats := bd.Stats()
+
+			t.Logf("target %.4f, measured %.4f, estimated %.4f, fill ratio %.3f",
+				tt.target, measured, stats.EstimatedFalsePosRate, stats.FillRatio)
+
+			if measured > 1.5*tt.target {
+				t.Errorf("measured false positive rate %.5f exceeds target %.5f", measured, tt.target)
+			}
+			if stats.EstimatedFalsePosRate > 1.5*tt.target {
+				t.Errorf("estimated false positive rate %.5f exceeds target %.5f", stats.EstimatedFalsePosRate, tt.target)
+			}
+			if got := bd.current.popCount(); got != bd.current.setBits {
+				t.Errorf("set bits counter = %d, want %d", bd.current.setBits, got)
+			}
+		})
+	}
+}
+
+func TestBloomReplayDetector_Rotation(t *testing.T) {
+	t.Parallel()
+
+	config.InitLogger()
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	bd := NewBloomReplayDetector(1000, 0.001, time.Minute)
+	bd.now = clock.Now
+	bd.windowStart = clock.Now()
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		wantErr error
+	}{
+		{
+			name:    "First solution",
+			advance: 0,
+			wantErr: nil,
+		},
+		{
+			name:    "Replay in the same window",
+			advance: 30 * time.Second,
+			wantErr: ErrAlreadySolved,
+		},
+		{
+			name:    "Replay in the next window",
+			advance: time.Minute,
+			wantErr: ErrAlreadySolved,
+		},
+		{
+			name:    "Forgotten after two windows",
+			advance: 2 * time.Minute,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		clock.Advance(tt.advance)
+		if err := bd.AddSolved(ctx, "signed"); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: BloomReplayDetector.AddSolved() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if stats := bd.Stats(); stats.Items != 1 {
+		t.Errorf("BloomReplayDetector.Stats() items = %d, want 1", stats.Items)
+	}
+}
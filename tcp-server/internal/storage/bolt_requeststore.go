@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	bolt "go.etcd.io/bbolt"
+)
+
+var requestsBucket = []byte("requests")
+
+type boltEntry struct {
+	IssuedAt time.Time `json:"issued_at"`
+	SolvedAt time.Time `json:"solved_at,omitempty"`
+	Solved   bool      `json:"solved"`
+	Payload  string    `json:"payload,omitempty"`
+}
+
+// BoltRequestStore keeps issued and solved requests in an embedded bbolt database,
+// so they survive server restarts. Every write is a separate serialized transaction.
+type BoltRequestStore struct {
+	db        *bolt.DB
+	ttl       time.Duration
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewBoltRequestStore opens (or creates) the database at path and drops the
+// requests that expired while the server was down.
+func NewBoltRequestStore(path string, ttl time.Duration, retention time.Duration) (*BoltRequestStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(requestsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	rs := &BoltRequestStore{
+		db:        db,
+		ttl:       ttl,
+		retention: retention,
+		now:       time.Now,
+	}
+
+	evicted, err := rs.Sweep()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	config.Logger.Infof("Request store recovered from '%s', %d expired requests dropped", path, evicted)
+
+	return rs, nil
+}
+
+func (rs *BoltRequestStore) Close() error {
+	return rs.db.Close()
+}
+
+func (rs *BoltRequestStore) Add(_ context.Context, request string, payload string) {
+	err := rs.db.Update(func(tx *bolt.Tx) error {
+		return putEntry(tx, request, boltEntry{IssuedAt: rs.now(), Payload: payload})
+	})
+	if err != nil {
+		config.Logger.Errorf("Failed to add request '%s' to store: %v", request, err)
+	}
+}
+
+func (rs *BoltRequestStore) Get(_ context.Context, request string) (string, error) {
+	var payload string
+
+	err := rs.db.View(func(tx *bolt.Tx) error {
+		entry, err := getEntry(tx, request)
+		if err != nil {
+			return err
+		}
+		if rs.expired(entry, rs.now()) {
+			return ErrExpired
+		}
+		if entry.Solved {
+			return ErrAlreadySolved
+		}
+		payload = entry.Payload
+		return nil
+	})
+
+	return payload, err
+}
+
+func (rs *BoltRequestStore) Consume(_ context.Context, request string) (string, error) {
+	var payload string
+
+	err := rs.db.Update(func(tx *bolt.Tx) error {
+		entry, err := getEntry(tx, request)
+		if err != nil {
+			return err
+		}
+
+		now := rs.now()
+		if rs.expired(entry, now) {
+			return ErrExpired
+		}
+		if entry.Solved {
+			return ErrAlreadySolved
+		}
+
+		entry.Solved = true
+		entry.SolvedAt = now
+		payload = entry.Payload
+		return putEntry(tx, request, entry)
+	})
+
+	return payload, err
+}
+
+func (rs *BoltRequestStore) AddSolved(_ context.Context, request string) error {
+	return rs.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(requestsBucket).Get([]byte(request)) != nil {
+			return ErrAlreadySolved
+		}
+
+		now := rs.now()
+		return putEntry(tx, request, boltEntry{IssuedAt: now, SolvedAt: now, Solved: true})
+	})
+}
+
+// RunSweeper evicts expired entries every interval until ctx is done.
+func (rs *BoltRequestStore) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			evicted, err := rs.Sweep()
+			if err != nil {
+				config.Logger.Errorf("Failed to evict expired requests: %v", err)
+				continue
+			}
+			if evicted > 0 {
+				config.Logger.Debugf("Evicted %d expired requests", evicted)
+			}
+		}
+	}
+}
+
+// Stats reads the whole bucket, skipping entries that can't be decoded.
+func (rs *BoltRequestStore) Stats(_ context.Context) (RequestStats, error) {
+	var stats RequestStats
+
+	err := rs.db.View(func(tx *bolt.Tx) error {
+		now := rs.now()
+		return tx.Bucket(requestsBucket).ForEach(func(_, value []byte) error {
+			var entry boltEntry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return nil
+			}
+			stats.add(requestEntry{solved: entry.Solved}, rs.expired(entry, now))
+			return nil
+		})
+	})
+
+	return stats, err
+}
+
+// Sweep deletes expired entries and returns how many were removed.
+func (rs *BoltRequestStore) Sweep() (int, error) {
+	evicted := 0
+
+	err := rs.db.Update(func(tx *bolt.Tx) error {
+		now := rs.now()
+		bucket := tx.Bucket(requestsBucket)
+
+		var expired [][]byte
+		err := bucket.ForEach(func(key, value []byte) error {
+			var entry boltEntry
+			if err := json.Unmarshal(value, &entry); err != nil || rs.expired(entry, now) {
+				expired = append(expired, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		evicted = len(expired)
+		return nil
+	})
+
+	return evicted, err
+}
+
+func (rs *BoltRequestStore) expired(entry boltEntry, now time.Time) bool {
+	return requestEntry{
+		issuedAt: entry.IssuedAt,
+		solvedAt: entry.SolvedAt,
+		solved:   entry.Solved,
+	}.expired(now, rs.ttl, rs.retention)
+}
+
+func getEntry(tx *bolt.Tx, request string) (boltEntry, error) {
+	value := tx.Bucket(requestsBucket).Get([]byte(request))
+	if value == nil {
+		return boltEntry{}, ErrNotFound
+	}
+
+	var entry boltEntry
+	if err := json.Unmarshal(value, &entry); err != nil {
+		return boltEntry{}, err
+	}
+	return entry, nil
+}
+
+func putEntry(tx *bolt.Tx, request string, entry boltEntry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(requestsBucket).Put([]byte(request), value)
+}
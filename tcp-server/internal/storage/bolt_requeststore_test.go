@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+)
+
+func openBoltStore(t *testing.T, path string, clock *fakeClock) *BoltRequestStore {
+	t.Helper()
+
+	rs, err := NewBoltRequestStore(path, time.Minute, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("NewBoltRequestStore() error = %v", err)
+	}
+	rs.now = clock.Now
+	return rs
+}
+
+func TestBoltRequestStore_Reopen(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "requests.db")
+	clock := &fakeClock{now: time.Now()}
+
+	rs := openBoltStore(t, path, clock)
+	rs.Add(ctx, "solved", "")
+	rs.Add(ctx, "pending", `{"author":"Seneca"}`)
+	if _, err := rs.Consume(ctx, "solved"); err != nil {
+		t.Fatalf("BoltRequestStore.Consume() error = %v", err)
+	}
+	if err := rs.AddSolved(ctx, "signed"); err != nil {
+		t.Fatalf("BoltRequestStore.AddSolved() error = %v", err)
+	}
+	if err := rs.Close(); err != nil {
+		t.Fatalf("BoltRequestStore.Close() error = %v", err)
+	}
+
+	rs = openBoltStore(t, path, clock)
+	defer rs.Close()
+
+	tests := []struct {
+		name        string
+		request     string
+		wantPayload string
+		wantErr     error
+	}{
+		{
+			name:    "Solution replayed after restart",
+			request: "solved",
+			wantErr: ErrAlreadySolved,
+		},
+		{
+			name:    "Signed solution replayed after restart",
+			request: "signed",
+			wantErr: ErrAlreadySolved,
+		},
+		{
+			name:        "Pending challenge solved after restart",
+			request:     "pending",
+			wantPayload: "{\"author\":\"Seneca\"}",
+			wantErr:     nil,
+		},
+		{
+			name:    "Unknown request",
+			request: "unknown",
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := rs.Consume(ctx, tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("BoltRequestStore.Consume() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if payload != tt.wantPayload {
+				t.Errorf("BoltRequestStore.Consume() payload = %v, want %v", payload, tt.wantPayload)
+			}
+		})
+	}
+}
+
+func TestBoltRequestStore_RecoverDropsExpired(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "requests.db")
+
+	rs := openBoltStore(t, path, &fakeClock{now: time.Now().Add(-time.Hour)})
+	rs.Add(ctx, "outdated", "")
+	rs.now = time.Now
+	rs.Add(ctx, "fresh", "")
+	if err := rs.Close(); err != nil {
+		t.Fatalf("BoltRequestStore.Close() error = %v", err)
+	}
+
+	rs = openBoltStore(t, path, &fakeClock{now: time.Now()})
+	defer rs.Close()
+
+	if _, err := rs.Get(ctx, "outdated"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("BoltRequestStore.Get() error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := rs.Get(ctx, "fresh"); err != nil {
+		t.Errorf("BoltRequestStore.Get() error = %v", err)
+	}
+}
+
+func TestBoltRequestStore_Sweep(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	rs := openBoltStore(t, filepath.Join(t.TempDir(), "requests.db"), clock)
+	defer rs.Close()
+
+	rs.Add(ctx, "outdated", "")
+	rs.Add(ctx, "solved", "")
+	if _, err := rs.Consume(ctx, "solved"); err != nil {
+		t.Fatalf("BoltRequestStore.Consume() error = %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+
+	if _, err := rs.Get(ctx, "outdated"); !errors.Is(err, ErrExpired) {
+		t.Errorf("BoltRequestStore.Get() error = %v, want %v", err, ErrExpired)
+	}
+	if evicted, err := rs.Sweep(); err != nil || evicted != 1 {
+		t.Errorf("BoltRequestStore.Sweep() = %v, %v, want 1, nil", evicted, err)
+	}
+	if _, err := rs.Get(ctx, "solved"); !errors.Is(err, ErrAlreadySolved) {
+		t.Errorf("BoltRequestStore.Get() error = %v, want %v", err, ErrAlreadySolved)
+	}
+}
+
+func TestBoltRequestStore_ConsumeConcurrent(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+
+	rs := openBoltStore(t, filepath.Join(t.TempDir(), "requests.db"), &fakeClock{now: time.Now()})
+	defer rs.Close()
+
+	rs.Add(ctx, "key", "")
+
+	var consumed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := rs.Consume(ctx, "key"); err == nil {
+				consumed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := consumed.Load(); got != 1 {
+		t.Errorf("BoltRequestStore.Consume() succeeded %d times, want 1", got)
+	}
+}
+
+func TestBoltRequestStore_Stats(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Now()}
+	rs := openBoltStore(t, filepath.Join(t.TempDir(), "requests.db"), clock)
+	defer rs.Close()
+
+	rs.Add(ctx, "expired", "")
+	clock.Advance(2 * time.Minute)
+	rs.Add(ctx, "pending", "")
+	rs.Add(ctx, "solved", "")
+	if _, err := rs.Consume(ctx, "solved"); err != nil {
+		t.Fatalf("BoltRequestStore.Consume() error = %v", err)
+	}
+
+	stats, err := rs.Stats(ctx)
+	if err != nil {
+		t.Fatalf("BoltRequestStore.Stats() error = %v", err)
+	}
+	if want := (RequestStats{Outstanding: 1, Solved: 1}); stats != want {
+		t.Errorf("BoltRequestStore.Stats() = %+v, want %+v", stats, want)
+	}
+}
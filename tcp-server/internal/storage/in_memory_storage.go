@@ -1,5 +1,7 @@
 package storage
 
+import "github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+
 var (
 	WordsOfWisdom = []string{
 		"The best dreams happen when you’re awake",
@@ -14,3 +16,12 @@ var (
 		"Only two things are infinite — the universe and human stupidity, and I’m not sure about the former",
 	}
 )
+
+// DefaultQuotes returns the built-in corpus used when no quotes file is configured.
+func DefaultQuotes() []model.Quote {
+	quotes := make([]model.Quote, 0, len(WordsOfWisdom))
+	for _, text := range WordsOfWisdom {
+		quotes = append(quotes, model.Quote{ID: QuoteID(text), Text: text, Language: "en"})
+	}
+	return quotes
+}
@@ -0,0 +1,46 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ReplayDetector is an autogenerated mock type for the ReplayDetector type
+type ReplayDetector struct {
+	mock.Mock
+}
+
+// AddSolved provides a mock function with given fields: ctx, request
+func (_m *ReplayDetector) AddSolved(ctx context.Context, request string) error {
+	ret := _m.Called(ctx, request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddSolved")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewReplayDetector creates a new instance of ReplayDetector. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewReplayDetector(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ReplayDetector {
+	mock := &ReplayDetector{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
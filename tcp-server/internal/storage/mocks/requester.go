@@ -5,6 +5,7 @@ package mocks
 import (
 	context "context"
 
+	storage "github.com/pullya/wow_tcp_server/tcp-server/internal/storage"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,28 +14,46 @@ type Requester struct {
 	mock.Mock
 }
 
-// Add provides a mock function with given fields: ctx, request
-func (_m *Requester) Add(ctx context.Context, request string) {
-	_m.Called(ctx, request)
+// Add provides a mock function with given fields: ctx, request, payload
+func (_m *Requester) Add(ctx context.Context, request string, payload string) {
+	_m.Called(ctx, request, payload)
 }
 
-// Get provides a mock function with given fields: ctx, request
-func (_m *Requester) Get(ctx context.Context, request string) (bool, error) {
+// AddSolved provides a mock function with given fields: ctx, request
+func (_m *Requester) AddSolved(ctx context.Context, request string) error {
 	ret := _m.Called(ctx, request)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Get")
+		panic("no return value specified for AddSolved")
 	}
 
-	var r0 bool
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Consume provides a mock function with given fields: ctx, request
+func (_m *Requester) Consume(ctx context.Context, request string) (string, error) {
+	ret := _m.Called(ctx, request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Consume")
+	}
+
+	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
 		return rf(ctx, request)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
 		r0 = rf(ctx, request)
 	} else {
-		r0 = ret.Get(0).(bool)
+		r0 = ret.Get(0).(string)
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
@@ -46,22 +65,60 @@ func (_m *Requester) Get(ctx context.Context, request string) (bool, error) {
 	return r0, r1
 }
 
-// Set provides a mock function with given fields: ctx, request
-func (_m *Requester) Set(ctx context.Context, request string) error {
+// Get provides a mock function with given fields: ctx, request
+func (_m *Requester) Get(ctx context.Context, request string) (string, error) {
 	ret := _m.Called(ctx, request)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Set")
+		panic("no return value specified for Get")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
 		r0 = rf(ctx, request)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(string)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Stats provides a mock function with given fields: ctx
+func (_m *Requester) Stats(ctx context.Context) (storage.RequestStats, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Stats")
+	}
+
+	var r0 storage.RequestStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (storage.RequestStats, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) storage.RequestStats); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(storage.RequestStats)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // NewRequester creates a new instance of Requester. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
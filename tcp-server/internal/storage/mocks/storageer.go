@@ -5,6 +5,7 @@ package mocks
 import (
 	context "context"
 
+	model "github.com/pullya/wow_tcp_server/tcp-server/internal/model"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,22 +14,60 @@ type Storageer struct {
 	mock.Mock
 }
 
-// GetRandomWOW provides a mock function with given fields: ctx
-func (_m *Storageer) GetRandomWOW(ctx context.Context) string {
-	ret := _m.Called(ctx)
+// GetDailyQuote provides a mock function with given fields: ctx, day
+func (_m *Storageer) GetDailyQuote(ctx context.Context, day string) (model.Quote, error) {
+	ret := _m.Called(ctx, day)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetRandomWOW")
+		panic("no return value specified for GetDailyQuote")
 	}
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func(context.Context) string); ok {
-		r0 = rf(ctx)
+	var r0 model.Quote
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (model.Quote, error)); ok {
+		return rf(ctx, day)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) model.Quote); ok {
+		r0 = rf(ctx, day)
+	} else {
+		r0 = ret.Get(0).(model.Quote)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, day)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRandomQuote provides a mock function with given fields: ctx, filter
+func (_m *Storageer) GetRandomQuote(ctx context.Context, filter model.QuoteFilter) (model.Quote, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRandomQuote")
+	}
+
+	var r0 model.Quote
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.QuoteFilter) (model.Quote, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.QuoteFilter) model.Quote); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(model.Quote)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.QuoteFilter) error); ok {
+		r1 = rf(ctx, filter)
 	} else {
-		r0 = ret.Get(0).(string)
+		r1 = ret.Error(1)
 	}
 
-	return r0
+	return r0, r1
 }
 
 // NewStorageer creates a new instance of Storageer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
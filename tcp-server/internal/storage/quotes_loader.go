@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+	"github.com/spaolacci/murmur3"
+	"gopkg.in/yaml.v2"
+)
+
+const MaxQuoteLength = 1000
+
+var ErrNoQuotes = errors.New("no quotes loaded")
+
+// QuoteProblem points to a single rejected entry of a quotes file.
+type QuoteProblem struct {
+	Location string
+	Reason   string
+}
+
+// QuotesFileError lists every rejected entry so the whole file can be fixed at once.
+type QuotesFileError struct {
+	Path     string
+	Problems []QuoteProblem
+}
+
+func (e *QuotesFileError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid quotes file %s: %d bad entries", e.Path, len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n\t%s: %s", p.Location, p.Reason)
+	}
+	return b.String()
+}
+
+type quoteEntry struct {
+	location string
+	quote    model.Quote
+}
+
+// LoadQuotes reads a quote corpus from a JSON, YAML, CSV or plain text file.
+// The format is chosen by the file extension, anything unknown is treated as plain text.
+func LoadQuotes(path string) ([]model.Quote, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []quoteEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		entries, err = parseJSONQuotes(data)
+	case ".yaml", ".yml":
+		entries, err = parseYAMLQuotes(data)
+	case ".csv":
+		entries, err = parseCSVQuotes(data)
+	default:
+		entries, err = parseTextQuotes(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing quotes file %s: %w", path, err)
+	}
+
+	return validateQuotes(path, entries)
+}
+
+// parseJSONQuotes is as strict as parseYAMLQuotes: unknown fields and data after
+// the list are rejected.
+func parseJSONQuotes(data []byte) ([]quoteEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var quotes []model.Quote
+	if err := dec.Decode(&quotes); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the quote list")
+	}
+	return indexedEntries(quotes), nil
+}
+
+func parseYAMLQuotes(data []byte) ([]quoteEntry, error) {
+	var quotes []model.Quote
+	if err := yaml.UnmarshalStrict(data, &quotes); err != nil {
+		return nil, err
+	}
+	return indexedEntries(quotes), nil
+}
+
+func indexedEntries(quotes []model.Quote) []quoteEntry {
+	entries := make([]quoteEntry, 0, len(quotes))
+	for i, q := range quotes {
+		entries = append(entries, quoteEntry{location: fmt.Sprintf("entry %d", i+1), quote: q})
+	}
+	return entries
+}
+
+// parseCSVQuotes expects a header row naming the columns
+// (id, text, author, source, language, tags); only "text" is mandatory.
+// Tags are separated by ";".
+func parseCSVQuotes(data []byte) ([]quoteEntry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["text"]; !ok {
+		return nil, errors.New("header has no text column")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	var entries []quoteEntry
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+
+		var tags []string
+		for _, tag := range strings.Split(field(record, "tags"), ";") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+
+		entries = append(entries, quoteEntry{
+			location: fmt.Sprintf("line %d", line),
+			quote: model.Quote{
+				ID:       field(record, "id"),
+				Text:     field(record, "text"),
+				Author:   field(record, "author"),
+				Source:   field(record, "source"),
+				Language: field(record, "language"),
+				Tags:     tags,
+			},
+		})
+	}
+	return entries, nil
+}
+
+// parseTextQuotes takes one quote per line, skipping blank lines and "#" comments.
+func parseTextQuotes(data []byte) ([]quoteEntry, error) {
+	var entries []quoteEntry
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		entries = append(entries, quoteEntry{location: fmt.Sprintf("line %d", line), quote: model.Quote{Text: text}})
+	}
+	return entries, scanner.Err()
+}
+
+// QuoteID derives a stable id from the quote text, so quotes without an explicit id
+// keep it across reloads and replicas.
+func QuoteID(text string) string {
+	return fmt.Sprintf("%016x", murmur3.Sum64([]byte(strings.ToLower(text))))
+}
+
+// normalizeQuote trims the quote fields and fills in a missing id. A non-empty
+// reason means the quote can't be served.
+func normalizeQuote(q model.Quote) (model.Quote, string) {
+	q.Text = strings.TrimSpace(q.Text)
+	q.ID = strings.TrimSpace(q.ID)
+	q.Author = strings.TrimSpace(q.Author)
+	q.Source = strings.TrimSpace(q.Source)
+	q.Language = strings.TrimSpace(q.Language)
+
+	switch {
+	case q.Text == "":
+		return q, "empty quote"
+	case utf8.RuneCountInString(q.Text) > MaxQuoteLength:
+		return q, fmt.Sprintf("quote is longer than %d characters", MaxQuoteLength)
+	}
+	if q.ID == "" {
+		q.ID = QuoteID(q.Text)
+	}
+	return q, ""
+}
+
+func validateQuotes(path string, entries []quoteEntry) ([]model.Quote, error) {
+	var problems []QuoteProblem
+	quotes := make([]model.Quote, 0, len(entries))
+	seen := make(map[string]string, len(entries))
+	seenIDs := make(map[string]string, len(entries))
+
+	for _, e := range entries {
+		q, reason := normalizeQuote(e.quote)
+
+		switch key := strings.ToLower(q.Text); {
+		case reason != "":
+			problems = append(problems, QuoteProblem{Location: e.location, Reason: reason})
+		case seen[key] != "":
+			problems = append(problems, QuoteProblem{Location: e.location, Reason: "duplicate of " + seen[key]})
+		default:
+			if seenIDs[q.ID] != "" {
+				problems = append(problems, QuoteProblem{Location: e.location, Reason: "duplicate id of " + seenIDs[q.ID]})
+				continue
+			}
+			seen[key] = e.location
+			seenIDs[q.ID] = e.location
+			quotes = append(quotes, q)
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &QuotesFileError{Path: path, Problems: problems}
+	}
+	if len(quotes) == 0 {
+		return nil, fmt.Errorf("%w from %s", ErrNoQuotes, path)
+	}
+	return quotes, nil
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+)
+
+func writeQuotesFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestLoadQuotes(t *testing.T) {
+	t.Parallel()
+
+	want := []model.Quote{
+		{ID: "fall", Text: "Fall seven times and stand up eight", Author: "Japanese proverb", Source: "Daruma", Language: "en", Tags: []string{"resilience"}},
+		{ID: QuoteID("Success is the child of audacity"), Text: "Success is the child of audacity", Author: "Benjamin Disraeli", Language: "en", Tags: []string{"success", "courage"}},
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []model.Quote
+	}{
+		{
+			name: "JSON",
+			file: "quotes.json",
+			content: `[
+				{"id": "fall", "text": "Fall seven times and stand up eight", "author": "Japanese proverb", "source": "Daruma", "language": "en", "tags": ["resilience"]},
+				{"text": "Success is the child of audacity", "author": "Benjamin Disraeli", "language": "en", "tags": ["success", "courage"]}
+			]`,
+			want: want,
+		},
+		{
+			name: "YAML",
+			file: "quotes.yml",
+			content: `- id: fall
+  text: Fall seven times and stand up eight
+  author: Japanese proverb
+  source: Daruma
+  language: en
+  tags: [resilience]
+- text: Success is the child of audacity
+  author: Benjamin Disraeli
+  language: en
+  tags: [success, courage]
+`,
+			want: want,
+		},
+		{
+			name: "CSV",
+			file: "quotes.csv",
+			content: `id,text,author,source,language,tags
+fall,Fall seven times and stand up eight,Japanese proverb,Daruma,en,resilience
+,"Success is the child of audacity",Benjamin Disraeli,,en,success; courage
+`,
+			want: want,
+		},
+		{
+			name: "CSV reordered columns",
+			file: "quotes.csv",
+			content: `language,text
+en,Fall seven times and stand up eight
+`,
+			want: []model.Quote{{ID: QuoteID("Fall seven times and stand up eight"), Text: "Fall seven times and stand up eight", Language: "en"}},
+		},
+		{
+			name: "Plain text",
+			file: "quotes.txt",
+			content: `# corpus
+Fall seven times and stand up eight
+
+  Success is the child of audacity
+`,
+			want: []model.Quote{
+				{ID: QuoteID("Fall seven times and stand up eight"), Text: "Fall seven times and stand up eight"},
+				{ID: QuoteID("Success is the child of audacity"), Text: "Success is the child of audacity"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := LoadQuotes(writeQuotesFile(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatalf("LoadQuotes() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadQuotes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadQuotes_invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		file         string
+		content      string
+		wantProblems []QuoteProblem
+		wantErr      error
+	}{
+		{
+			name: "Text problems",
+			file: "quotes.txt",
+			content: "Fall seven times and stand up eight\n" +
+				"Success is the child of audacity\n" +
+				"fall seven times and stand up eight\n" +
+				strings.Repeat("a", MaxQuoteLength+1) + "\n",
+			wantProblems: []QuoteProblem{
+				{Location: "line 3", Reason: "duplicate of line 1"},
+				{Location: "line 4", Reason: "quote is longer than 1000 characters"},
+			},
+		},
+		{
+			name:    "JSON empty text",
+			file:    "quotes.json",
+			content: `[{"text": "Success is the child of audacity"}, {"text": "  ", "author": "Nobody"}]`,
+			wantProblems: []QuoteProblem{
+				{Location: "entry 2", Reason: "empty quote"},
+			},
+		},
+		{
+			name:    "CSV empty text",
+			file:    "quotes.csv",
+			content: "text,author\nSuccess is the child of audacity,Benjamin Disraeli\n,Nobody\n",
+			wantProblems: []QuoteProblem{
+				{Location: "line 3", Reason: "empty quote"},
+			},
+		},
+		{
+			name:    "JSON duplicate id",
+			file:    "quotes.json",
+			content: `[{"id": "a", "text": "First"}, {"id": "a", "text": "Second"}]`,
+			wantProblems: []QuoteProblem{
+				{Location: "entry 2", Reason: "duplicate id of entry 1"},
+			},
+		},
+		{
+			name:    "Empty corpus",
+			file:    "quotes.txt",
+			content: "# nothing here\n\n",
+			wantErr: ErrNoQuotes,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := writeQuotesFile(t, tt.file, tt.content)
+			_, err := LoadQuotes(path)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("LoadQuotes() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			var fileErr *QuotesFileError
+			if !errors.As(err, &fileErr) {
+				t.Fatalf("LoadQuotes() error = %v, want *QuotesFileError", err)
+			}
+			if fileErr.Path != path {
+				t.Errorf("QuotesFileError.Path = %v, want %v", fileErr.Path, path)
+			}
+			if !reflect.DeepEqual(fileErr.Problems, tt.wantProblems) {
+				t.Errorf("QuotesFileError.Problems = %v, want %v", fileErr.Problems, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func TestLoadQuotes_malformed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "JSON syntax", file: "quotes.json", content: `[{"text": "unterminated}]`},
+		{name: "JSON unknown field", file: "quotes.json", content: `[{"text": "hello", "quote": "world"}]`},
+		{name: "JSON data after the list", file: "quotes.json", content: `[{"text": "hello"}] [{"text": "world"}]`},
+		{name: "YAML unknown field", file: "quotes.yaml", content: "- text: hello\n  quote: world\n"},
+		{name: "CSV without text column", file: "quotes.csv", content: "author,language\nNobody,en\n"},
+		{name: "Missing file", file: "", content: ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "missing.txt")
+			if tt.file != "" {
+				path = writeQuotesFile(t, tt.file, tt.content)
+			}
+			if _, err := LoadQuotes(path); err == nil {
+				t.Errorf("LoadQuotes() error = nil, want error")
+			}
+		})
+	}
+}
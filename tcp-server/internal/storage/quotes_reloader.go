@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/metrics"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+)
+
+// QuotesReloader reloads the quotes file into a Storage when the file changes
+// or a reload is requested. A file that fails to load leaves the current corpus in place.
+// Quotes added via the admin API are kept unless the file now has a quote with the same
+// id or text; quotes of the file deleted via the admin API come back.
+type QuotesReloader struct {
+	storage *Storage
+	path    string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	loaded  []model.Quote // the file corpus of the last reload
+}
+
+// NewQuotesReloader expects the storage to hold the quotes of the file.
+func NewQuotesReloader(storage *Storage, path string) *QuotesReloader {
+	qr := &QuotesReloader{
+		storage: storage,
+		path:    path,
+		loaded:  storage.Quotes(),
+	}
+	if info, err := os.Stat(path); err == nil {
+		qr.modTime, qr.size = info.ModTime(), info.Size()
+	}
+	return qr
+}
+
+// Run polls the file every interval (0 disables polling) and reloads
+// unconditionally on every value received from trigger.
+func (qr *QuotesReloader) Run(ctx context.Context, interval time.Duration, trigger <-chan os.Signal) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-trigger:
+			config.Logger.Infof("Received signal %v. Reloading quotes from %s", sig, qr.path)
+			_ = qr.Reload()
+		case <-tick:
+			if qr.changed() {
+				config.Logger.Infof("Quotes file %s changed. Reloading", qr.path)
+				_ = qr.Reload()
+			}
+		}
+	}
+}
+
+func (qr *QuotesReloader) Reload() error {
+	qr.mu.Lock()
+	defer qr.mu.Unlock()
+
+	info, err := os.Stat(qr.path)
+	if err == nil {
+		// remember the failed version as well, so polling does not retry it until the next edit
+		qr.modTime, qr.size = info.ModTime(), info.Size()
+	}
+
+	quotes, err := LoadQuotes(qr.path)
+	if err != nil {
+		metrics.QuotesReloads.WithLabelValues(metrics.ReloadError).Inc()
+		config.Logger.Errorf("Quotes reload rejected, keeping %d quotes: %v", qr.storage.Len(), err)
+		return err
+	}
+	if reflect.DeepEqual(quotes, qr.loaded) {
+		metrics.QuotesReloads.WithLabelValues(metrics.ReloadUnchanged).Inc()
+		config.Logger.Infof("Quotes file %s is unchanged, keeping %d quotes", qr.path, qr.storage.Len())
+		return nil
+	}
+
+	var kept int
+	qr.storage.update(func(current []model.Quote) []model.Quote {
+		var merged []model.Quote
+		merged, kept = mergeAdded(quotes, qr.loaded, current)
+		return merged
+	})
+	qr.loaded = quotes
+	metrics.QuotesReloads.WithLabelValues(metrics.ReloadOK).Inc()
+	config.Logger.Infof("Quotes reloaded: %d quotes from %s, %d added via admin API kept", len(quotes), qr.path, kept)
+
+	return nil
+}
+
+// mergeAdded appends to the file corpus the current quotes that weren't in the previous
+// one, i.e. were added via the admin API, unless they clash with a quote of the file.
+func mergeAdded(file, previous, current []model.Quote) ([]model.Quote, int) {
+	fromFile := make(map[string]bool, len(previous))
+	for _, q := range previous {
+		fromFile[q.ID] = true
+	}
+	ids := make(map[string]bool, len(file))
+	texts := make(map[string]bool, len(file))
+	for _, q := range file {
+		ids[q.ID] = true
+		texts[strings.ToLower(q.Text)] = true
+	}
+
+	merged := append([]model.Quote(nil), file...)
+	for _, q := range current {
+		if fromFile[q.ID] || ids[q.ID] || texts[strings.ToLower(q.Text)] {
+			continue
+		}
+		merged = append(merged, q)
+	}
+	return merged, len(merged) - len(file)
+}
+
+func (qr *QuotesReloader) changed() bool {
+	info, err := os.Stat(qr.path)
+	if err != nil {
+		return false
+	}
+
+	qr.mu.Lock()
+	defer qr.mu.Unlock()
+
+	return !info.ModTime().Equal(qr.modTime) || info.Size() != qr.size
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/metrics"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+)
+
+// reloads counts the reloads with each result so far.
+func reloads() map[string]float64 {
+	counts := make(map[string]float64)
+	for _, result := range []string{metrics.ReloadOK, metrics.ReloadError, metrics.ReloadUnchanged} {
+		counts[result] = testutil.ToFloat64(metrics.QuotesReloads.WithLabelValues(result))
+	}
+	return counts
+}
+
+func TestQuotesReloader_Reload(t *testing.T) {
+	config.InitLogger()
+
+	path := writeQuotesFile(t, "quotes.txt", "first\n")
+	quotes, err := LoadQuotes(path)
+	if err != nil {
+		t.Fatalf("LoadQuotes() error = %v", err)
+	}
+	s := New(quotes)
+	qr := NewQuotesReloader(s, path)
+	before := reloads()
+
+	if err := qr.Reload(); err != nil {
+		t.Fatalf("QuotesReloader.Reload() error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte("second\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := qr.Reload(); err != nil {
+		t.Fatalf("QuotesReloader.Reload() error = %v", err)
+	}
+	if got := randomText(s); got != "second" {
+		t.Errorf("Storage.GetRandomQuote() = %v, want %v", got, "second")
+	}
+
+	if err := os.WriteFile(path, []byte("third\nthird\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := qr.Reload(); err == nil {
+		t.Fatalf("QuotesReloader.Reload() error = nil, want error for duplicates")
+	}
+	if got := randomText(s); got != "second" {
+		t.Errorf("Storage.GetRandomQuote() after rejected reload = %v, want %v", got, "second")
+	}
+
+	after := reloads()
+	for result, want := range map[string]float64{metrics.ReloadOK: 1, metrics.ReloadError: 1, metrics.ReloadUnchanged: 1} {
+		if got := after[result] - before[result]; got != want {
+			t.Errorf("wow_quotes_reloads_total{result=%q} grew by %v, want %v", result, got, want)
+		}
+	}
+}
+
+func TestQuotesReloader_ReloadKeepsAdminQuotes(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+
+	path := writeQuotesFile(t, "quotes.txt", "first\nsecond\n")
+	quotes, err := LoadQuotes(path)
+	if err != nil {
+		t.Fatalf("LoadQuotes() error = %v", err)
+	}
+	s := New(quotes)
+	qr := NewQuotesReloader(s, path)
+
+	for _, text := range []string{"added", "clashes"} {
+		if _, err := s.AddQuote(ctx, model.Quote{Text: text}); err != nil {
+			t.Fatalf("Storage.AddQuote() error = %v", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte("second\nthird\nClashes\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := qr.Reload(); err != nil {
+		t.Fatalf("QuotesReloader.Reload() error = %v", err)
+	}
+
+	var got []string
+	for _, q := range s.Quotes() {
+		got = append(got, q.Text)
+	}
+	if want := []string{"second", "third", "Clashes", "added"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Storage.Quotes() after reload = %v, want %v", got, want)
+	}
+}
+
+func TestQuotesReloader_Run(t *testing.T) {
+	config.InitLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	path := writeQuotesFile(t, "quotes.txt", "first\n")
+	s := New(DefaultQuotes())
+	qr := NewQuotesReloader(s, path)
+	before := reloads()
+
+	trigger := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		qr.Run(ctx, 10*time.Millisecond, trigger)
+		close(done)
+	}()
+
+	// the file has not changed since the reloader was created, only the signal loads it
+	trigger <- syscall.SIGHUP
+	waitForQuote(t, s, "first")
+
+	if err := os.WriteFile(path, []byte("second, longer than before\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	waitForQuote(t, s, "second, longer than before")
+
+	cancel()
+	<-done
+
+	if got := reloads()[metrics.ReloadOK] - before[metrics.ReloadOK]; got != 2 {
+		t.Errorf("wow_quotes_reloads_total{result=\"ok\"} grew by %v, want 2", got)
+	}
+}
+
+func TestStorage_SwapConcurrent(t *testing.T) {
+	s := New(DefaultQuotes())
+	path := writeQuotesFile(t, "quotes.txt", "only one\n")
+	quotes, err := LoadQuotes(path)
+	if err != nil {
+		t.Fatalf("LoadQuotes() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if randomText(s) == "" {
+					t.Error("Storage.GetRandomQuote() returned empty quote")
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			s.Swap(quotes)
+		} else {
+			s.Swap(DefaultQuotes())
+		}
+	}
+	wg.Wait()
+}
+
+func waitForQuote(t *testing.T, s *Storage, want string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if s.Len() == 1 && randomText(s) == want {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("quote %q was not loaded", want)
+}
+
+func randomText(s *Storage) string {
+	q, err := s.GetRandomQuote(context.Background(), model.QuoteFilter{})
+	if err != nil {
+		return ""
+	}
+	return q.Text
+}
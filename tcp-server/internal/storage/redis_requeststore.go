@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisRequestStore keeps requests in a Redis-compatible server shared by all
+// tcp-server replicas. An issued request is a key holding its payload, a solved one is
+// a separate marker key. Expiry is decided by the key TTLs, so it doesn't depend on the
+// clocks of the replicas.
+type RedisRequestStore struct {
+	client    *redis.Client
+	prefix    string
+	ttl       time.Duration
+	retention time.Duration
+}
+
+// Results of checkScript.
+const (
+	checkOK = iota
+	checkNotFound
+	checkSolved
+	checkExpired
+)
+
+// checkScript returns the payload of an issued request that is neither solved nor expired,
+// and with ARGV[2] set marks it solved in the same step. The issued key lives ttl+retention,
+// so the request has expired once less than retention is left. The marker expires together
+// with the issued key, so it covers at least the rest of the challenge lifetime.
+var checkScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	return {2}
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	return {1}
+end
+if ttl <= tonumber(ARGV[1]) then
+	return {3}
+end
+if ARGV[2] == '1' then
+	redis.call('SET', KEYS[2], 1, 'PX', ttl)
+end
+return {0, redis.call('GET', KEYS[1])}
+`)
+
+func NewRedisRequestStore(client *redis.Client, prefix string, ttl time.Duration, retention time.Duration) *RedisRequestStore {
+	return &RedisRequestStore{
+		client:    client,
+		prefix:    prefix,
+		ttl:       ttl,
+		retention: retention,
+	}
+}
+
+// Add stores the payload. The key outlives the challenge by retention, so a late
+// solution is still reported as expired rather than unknown.
+func (rs *RedisRequestStore) Add(ctx context.Context, request string, payload string) {
+	if err := rs.client.SetNX(ctx, rs.issuedKey(request), payload, rs.ttl+rs.retention).Err(); err != nil {
+		config.Logger.Errorf("Failed to add request '%s' to store: %v", request, err)
+	}
+}
+
+func (rs *RedisRequestStore) Get(ctx context.Context, request string) (string, error) {
+	return rs.check(ctx, request, false)
+}
+
+// Consume checks and marks the request solved in one script, so concurrent solutions
+// of the same request on any replica succeed exactly once.
+func (rs *RedisRequestStore) Consume(ctx context.Context, request string) (string, error) {
+	return rs.check(ctx, request, true)
+}
+
+// AddSolved sets the solved marker only if it doesn't exist yet. Signed challenges
+// aren't stored, so the marker is kept for retention.
+func (rs *RedisRequestStore) AddSolved(ctx context.Context, request string) error {
+	ok, err := rs.client.SetNX(ctx, rs.solvedKey(request), 1, rs.retention).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAlreadySolved
+	}
+	return nil
+}
+
+func (rs *RedisRequestStore) check(ctx context.Context, request string, consume bool) (string, error) {
+	mark := "0"
+	if consume {
+		mark = "1"
+	}
+	result, err := checkScript.Run(ctx, rs.client,
+		[]string{rs.issuedKey(request), rs.solvedKey(request)},
+		rs.retention.Milliseconds(), mark,
+	).Slice()
+	if err != nil {
+		return "", err
+	}
+
+	switch result[0] {
+	case int64(checkOK):
+		payload, _ := result[1].(string)
+		return payload, nil
+	case int64(checkNotFound):
+		return "", ErrNotFound
+	case int64(checkSolved):
+		return "", ErrAlreadySolved
+	case int64(checkExpired):
+		return "", ErrExpired
+	}
+	return "", errors.New("unexpected result of the request check")
+}
+
+// Stats scans the keys of the store, so it is meant for occasional inspection only.
+// With several replicas sharing the prefix the counts cover all of them.
+func (rs *RedisRequestStore) Stats(ctx context.Context) (RequestStats, error) {
+	var stats RequestStats
+	solved := make(map[string]bool)
+
+	iter := rs.client.Scan(ctx, 0, rs.solvedKey("*"), 0).Iterator()
+	for iter.Next(ctx) {
+		solved[strings.TrimPrefix(iter.Val(), rs.solvedKey(""))] = true
+	}
+	if err := iter.Err(); err != nil {
+		return RequestStats{}, err
+	}
+	stats.Solved = len(solved)
+
+	iter = rs.client.Scan(ctx, 0, rs.issuedKey("*"), 0).Iterator()
+	for iter.Next(ctx) {
+		request := strings.TrimPrefix(iter.Val(), rs.issuedKey(""))
+		if solved[request] {
+			continue
+		}
+		_, err := rs.Get(ctx, request)
+		switch {
+		case err == nil:
+			stats.Outstanding++
+		case !errors.Is(err, ErrExpired) && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrAlreadySolved):
+			return RequestStats{}, err
+		}
+	}
+	return stats, iter.Err()
+}
+
+func (rs *RedisRequestStore) issuedKey(request string) string {
+	return rs.prefix + "request:" + request
+}
+
+func (rs *RedisRequestStore) solvedKey(request string) string {
+	return rs.prefix + "solved:" + request
+}
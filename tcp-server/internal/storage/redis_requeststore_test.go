@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T, ttl time.Duration, retention time.Duration) (*RedisRequestStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewRedisRequestStore(client, "wow:", ttl, retention), mr
+}
+
+func TestRedisRequestStore_Consume(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	rs, mr := newTestRedisStore(t, time.Minute, 5*time.Minute)
+
+	rs.Add(ctx, "key", `{"author":"Seneca"}`)
+
+	tests := []struct {
+		name        string
+		request     string
+		wantPayload string
+		wantErr     error
+	}{
+		{
+			name:        "Success",
+			request:     "key",
+			wantPayload: "{\"author\":\"Seneca\"}",
+			wantErr:     nil,
+		},
+		{
+			name:    "Already solved",
+			request: "key",
+			wantErr: ErrAlreadySolved,
+		},
+		{
+			name:    "Unknown request",
+			request: "unknown",
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := rs.Consume(ctx, tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RedisRequestStore.Consume() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if payload != tt.wantPayload {
+				t.Errorf("RedisRequestStore.Consume() payload = %v, want %v", payload, tt.wantPayload)
+			}
+		})
+	}
+
+	if !mr.Exists("wow:solved:key") {
+		t.Errorf("solved marker is not stored under the key prefix")
+	}
+}
+
+func TestRedisRequestStore_Expiry(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	rs, mr := newTestRedisStore(t, time.Minute, 5*time.Minute)
+
+	rs.Add(ctx, "pending", "")
+	rs.Add(ctx, "solved", "")
+	if _, err := rs.Consume(ctx, "solved"); err != nil {
+		t.Fatalf("RedisRequestStore.Consume() error = %v", err)
+	}
+
+	mr.FastForward(2 * time.Minute)
+
+	if _, err := rs.Consume(ctx, "pending"); !errors.Is(err, ErrExpired) {
+		t.Errorf("RedisRequestStore.Consume() error = %v, want %v", err, ErrExpired)
+	}
+	if _, err := rs.Get(ctx, "solved"); !errors.Is(err, ErrAlreadySolved) {
+		t.Errorf("RedisRequestStore.Get() error = %v, want %v", err, ErrAlreadySolved)
+	}
+
+	mr.FastForward(5 * time.Minute)
+
+	if mr.Exists("wow:request:pending") || mr.Exists("wow:solved:solved") {
+		t.Errorf("expired requests are still stored")
+	}
+	if _, err := rs.Get(ctx, "solved"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RedisRequestStore.Get() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestRedisRequestStore_RetentionShorterThanTTL(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	rs, mr := newTestRedisStore(t, 5*time.Minute, time.Minute)
+
+	rs.Add(ctx, "key", "")
+	if _, err := rs.Consume(ctx, "key"); err != nil {
+		t.Fatalf("RedisRequestStore.Consume() error = %v", err)
+	}
+
+	mr.FastForward(2 * time.Minute)
+
+	if _, err := rs.Consume(ctx, "key"); !errors.Is(err, ErrAlreadySolved) {
+		t.Errorf("RedisRequestStore.Consume() of a solved request within its ttl error = %v, want %v", err, ErrAlreadySolved)
+	}
+}
+
+func TestRedisRequestStore_AddSolved(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	rs, _ := newTestRedisStore(t, time.Minute, 5*time.Minute)
+
+	var accepted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rs.AddSolved(ctx, "signed") == nil {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := accepted.Load(); got != 1 {
+		t.Errorf("RedisRequestStore.AddSolved() succeeded %d times, want 1", got)
+	}
+}
+
+func TestRedisRequestStore_Stats(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	rs, mr := newTestRedisStore(t, time.Minute, 5*time.Minute)
+
+	rs.Add(ctx, "expired", "")
+	mr.FastForward(2 * time.Minute)
+	rs.Add(ctx, "pending", "")
+	rs.Add(ctx, "solved", "")
+	if _, err := rs.Consume(ctx, "solved"); err != nil {
+		t.Fatalf("RedisRequestStore.Consume() error = %v", err)
+	}
+
+	stats, err := rs.Stats(ctx)
+	if err != nil {
+		t.Fatalf("RedisRequestStore.Stats() error = %v", err)
+	}
+	if want := (RequestStats{Outstanding: 1, Solved: 1}); stats != want {
+		t.Errorf("RedisRequestStore.Stats() = %+v, want %+v", stats, want)
+	}
+}
@@ -3,77 +3,267 @@ package storage
 import (
 	"context"
 	"errors"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/metrics"
 )
 
 type ShardFunc func(data []byte) uint32
 
+var (
+	ErrNotFound      = errors.New("request not found")
+	ErrAlreadySolved = errors.New("request already solved")
+	ErrExpired       = errors.New("request expired")
+)
+
+// Requester keeps issued requests. The payload passed to Add is opaque to the store
+// and is handed back by Get, which leaves the request as is, and by Consume.
+//
 //go:generate mockery --name=Requester --output=mocks --case=underscore
 type Requester interface {
-	Add(ctx context.Context, request string)
-	Get(ctx context.Context, request string) (bool, error)
-	Set(ctx context.Context, request string) error
+	Add(ctx context.Context, request string, payload string)
+	Get(ctx context.Context, request string) (string, error)
+	Consume(ctx context.Context, request string) (string, error)
+	AddSolved(ctx context.Context, request string) error
+	Stats(ctx context.Context) (RequestStats, error)
+}
+
+// RequestStats counts the requests a store keeps. Expired ones that weren't swept yet are skipped.
+type RequestStats struct {
+	Outstanding int `json:"outstanding"`
+	Solved      int `json:"solved"`
+}
+
+// ReplayDetector remembers solved signed challenges. Every Requester is one,
+// BloomReplayDetector trades exactness for bounded memory.
+//
+//go:generate mockery --name=ReplayDetector --output=mocks --case=underscore
+type ReplayDetector interface {
+	AddSolved(ctx context.Context, request string) error
+}
+
+// requestEntry is an issued challenge. An unsolved entry expires ttl after issuedAt,
+// a solved one is kept for retention after solvedAt to reject replays.
+type requestEntry struct {
+	issuedAt time.Time
+	solvedAt time.Time
+	solved   bool
+	payload  string
+}
+
+type requestShard struct {
+	mu      sync.RWMutex
+	entries map[string]*requestEntry
+	size    prometheus.Gauge
+}
+
+// updateSize must be called with the shard locked after entries are added or removed.
+func (s *requestShard) updateSize() {
+	s.size.Set(float64(len(s.entries)))
 }
 
 type RequestStore struct {
-	shards    map[uint32]map[string]bool
+	shards    []*requestShard
 	shardFunc func(data string) uint32
-	mu        *sync.Mutex
+	ttl       time.Duration
+	retention time.Duration
+	now       func() time.Time
 }
 
-func NewRequestStore(shardFunc func(data string) uint32) *RequestStore {
+// NewRequestStore pre-allocates shardsCnt shards, each guarded by its own lock.
+// shardFunc must map a request to a shard index below shardsCnt.
+func NewRequestStore(shardsCnt int, shardFunc func(data string) uint32, ttl time.Duration, retention time.Duration) *RequestStore {
+	shards := make([]*requestShard, shardsCnt)
+	for i := range shards {
+		shards[i] = &requestShard{
+			entries: make(map[string]*requestEntry),
+			size:    metrics.RequestStoreEntries.WithLabelValues(strconv.Itoa(i)),
+		}
+		shards[i].updateSize()
+	}
+
 	return &RequestStore{
-		shards:    make(map[uint32]map[string]bool, config.Config.ShardsCnt),
+		shards:    shards,
 		shardFunc: shardFunc,
-		mu:        &sync.Mutex{},
+		ttl:       ttl,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+func (rs *RequestStore) Add(_ context.Context, request string, payload string) {
+	shard, err := rs.shard(request)
+	if err != nil {
+		return
+	}
+	now := rs.now()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.entries[request] = &requestEntry{issuedAt: now, payload: payload}
+	shard.updateSize()
+}
+
+// Get returns the payload of an issued request that is neither expired nor solved.
+func (rs *RequestStore) Get(_ context.Context, request string) (string, error) {
+	shard, err := rs.shard(request)
+	if err != nil {
+		return "", err
+	}
+	now := rs.now()
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	entry, ok := shard.entries[request]
+	if !ok {
+		return "", ErrNotFound
 	}
+	if rs.expired(entry, now) {
+		return "", ErrExpired
+	}
+	if entry.solved {
+		return "", ErrAlreadySolved
+	}
+	return entry.payload, nil
 }
 
-func (rs *RequestStore) Add(_ context.Context, request string) {
-	shardKey := rs.shardFunc(request)
+// Consume marks an issued request as solved. Existence, expiry and the solved flag
+// are checked under the same lock, so only one caller can consume a request.
+func (rs *RequestStore) Consume(_ context.Context, request string) (string, error) {
+	shard, err := rs.shard(request)
+	if err != nil {
+		return "", err
+	}
+	now := rs.now()
 
-	rs.mu.Lock()
-	defer rs.mu.Unlock()
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
 
-	if _, ok := rs.shards[shardKey]; !ok {
-		shard := make(map[string]bool)
-		rs.shards[shardKey] = shard
+	entry, ok := shard.entries[request]
+	if !ok {
+		return "", ErrNotFound
+	}
+	if rs.expired(entry, now) {
+		return "", ErrExpired
 	}
+	if entry.solved {
+		return "", ErrAlreadySolved
+	}
+	entry.solved = true
+	entry.solvedAt = now
+	return entry.payload, nil
+}
 
-	rs.shards[shardKey][request] = false
+// AddSolved records a request that was never added to the store (e.g. a signed
+// challenge) as solved. It fails with ErrAlreadySolved if the request is known.
+func (rs *RequestStore) AddSolved(_ context.Context, request string) error {
+	shard, err := rs.shard(request)
+	if err != nil {
+		return err
+	}
+	now := rs.now()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if _, ok := shard.entries[request]; ok {
+		return ErrAlreadySolved
+	}
+	shard.entries[request] = &requestEntry{
+		issuedAt: now,
+		solvedAt: now,
+		solved:   true,
+	}
+	shard.updateSize()
+	return nil
 }
 
-func (rs *RequestStore) Get(_ context.Context, request string) (bool, error) {
-	shardKey := rs.shardFunc(request)
+func (rs *RequestStore) Stats(_ context.Context) (RequestStats, error) {
+	var stats RequestStats
+	now := rs.now()
 
-	rs.mu.Lock()
-	defer rs.mu.Unlock()
+	for _, shard := range rs.shards {
+		shard.mu.RLock()
+		for _, entry := range shard.entries {
+			stats.add(*entry, rs.expired(entry, now))
+		}
+		shard.mu.RUnlock()
+	}
+	return stats, nil
+}
+
+// RunSweeper evicts expired entries every interval until ctx is done.
+func (rs *RequestStore) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if evicted := rs.Sweep(); evicted > 0 {
+				config.Logger.Debugf("Evicted %d expired requests", evicted)
+			}
+		}
+	}
+}
 
-	if _, ok := rs.shards[shardKey]; !ok {
-		return false, errors.New("shard not found")
+// Sweep evicts expired entries shard by shard and returns how many were removed.
+func (rs *RequestStore) Sweep() int {
+	evicted := 0
+	for _, shard := range rs.shards {
+		evicted += rs.sweepShard(shard, rs.now())
 	}
+	return evicted
+}
 
-	if status, ok := rs.shards[shardKey][request]; ok {
-		return status, nil
+func (rs *RequestStore) sweepShard(shard *requestShard, now time.Time) int {
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	evicted := 0
+	for request, entry := range shard.entries {
+		if rs.expired(entry, now) {
+			delete(shard.entries, request)
+			evicted++
+		}
 	}
-	return false, errors.New("request not found")
+	shard.updateSize()
+	return evicted
 }
 
-func (rs *RequestStore) Set(_ context.Context, request string) error {
-	shardKey := rs.shardFunc(request)
+func (rs *RequestStore) shard(request string) (*requestShard, error) {
+	key := rs.shardFunc(request)
+	if int(key) >= len(rs.shards) {
+		return nil, errors.New("shard not found")
+	}
+	return rs.shards[key], nil
+}
 
-	rs.mu.Lock()
-	defer rs.mu.Unlock()
+func (rs *RequestStore) expired(entry *requestEntry, now time.Time) bool {
+	return entry.expired(now, rs.ttl, rs.retention)
+}
 
-	if _, ok := rs.shards[shardKey]; !ok {
-		return errors.New("shard not found")
+func (s *RequestStats) add(entry requestEntry, expired bool) {
+	switch {
+	case expired:
+	case entry.solved:
+		s.Solved++
+	default:
+		s.Outstanding++
 	}
+}
 
-	if _, ok := rs.shards[shardKey][request]; !ok {
-		return errors.New("request not found")
+func (e requestEntry) expired(now time.Time, ttl time.Duration, retention time.Duration) bool {
+	if e.solved {
+		return now.After(e.solvedAt.Add(retention))
 	}
-	rs.shards[shardKey][request] = true
-	return nil
+	return now.After(e.issuedAt.Add(ttl))
 }
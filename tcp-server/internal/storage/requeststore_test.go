@@ -2,8 +2,15 @@ package storage
 
 import (
 	"context"
-	"sync"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/metrics"
 )
 
 func TestRequestStore_Add(t *testing.T) {
@@ -15,40 +22,397 @@ func TestRequestStore_Add(t *testing.T) {
 
 		return hashValue % uint32(numShards)
 	}
-	rs := make(map[uint32]map[string]bool, 8)
 
 	type fields struct {
-		shards    map[uint32]map[string]bool
+		shardsCnt int
 		shardFunc func(data string) uint32
-		mutex     *sync.Mutex
 	}
 	type args struct {
 		ctx     context.Context
 		request string
 	}
 	tests := []struct {
-		name   string
-		fields fields
-		args   args
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
 	}{
 		{
-			name: "",
+			name: "Success",
 			fields: fields{
-				shards:    rs,
+				shardsCnt: 8,
 				shardFunc: sf,
-				mutex:     &sync.Mutex{},
 			},
-			args: args{ctx: ctx, request: "key"},
+			args:    args{ctx: ctx, request: "key"},
+			wantErr: false,
+		},
+		{
+			name: "Shard out of range",
+			fields: fields{
+				shardsCnt: 8,
+				shardFunc: func(string) uint32 { return 8 },
+			},
+			args:    args{ctx: ctx, request: "key"},
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			rs := &RequestStore{
-				shards:    tt.fields.shards,
-				shardFunc: tt.fields.shardFunc,
-				mu:        tt.fields.mutex,
+			rs := NewRequestStore(tt.fields.shardsCnt, tt.fields.shardFunc, time.Minute, time.Minute)
+			rs.Add(tt.args.ctx, tt.args.request, "")
+
+			if _, err := rs.Get(tt.args.ctx, tt.args.request); (err != nil) != tt.wantErr {
+				t.Errorf("RequestStore.Get() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			rs.Add(tt.args.ctx, tt.args.request)
 		})
 	}
 }
+
+func TestRequestStore_AddSolved(t *testing.T) {
+	ctx := context.Background()
+	rs := NewRequestStore(1, func(string) uint32 { return 0 }, time.Minute, time.Minute)
+
+	if err := rs.AddSolved(ctx, "signed"); err != nil {
+		t.Fatalf("RequestStore.AddSolved() error = %v", err)
+	}
+	if err := rs.AddSolved(ctx, "signed"); !errors.Is(err, ErrAlreadySolved) {
+		t.Errorf("RequestStore.AddSolved() error = %v, want %v", err, ErrAlreadySolved)
+	}
+	if _, err := rs.Get(ctx, "signed"); !errors.Is(err, ErrAlreadySolved) {
+		t.Errorf("RequestStore.Get() error = %v, want %v", err, ErrAlreadySolved)
+	}
+}
+
+func TestRequestStore_Consume(t *testing.T) {
+	ctx := context.Background()
+	rs := NewRequestStore(1, func(string) uint32 { return 0 }, time.Minute, time.Minute)
+	rs.Add(ctx, "key", `{"author":"Seneca"}`)
+
+	tests := []struct {
+		name        string
+		request     string
+		wantPayload string
+		wantErr     error
+	}{
+		{
+			name:        "Success",
+			request:     "key",
+			wantPayload: "{\"author\":\"Seneca\"}",
+			wantErr:     nil,
+		},
+		{
+			name:    "Already solved",
+			request: "key",
+			wantErr: ErrAlreadySolved,
+		},
+		{
+			name:    "Unknown request",
+			request: "unknown",
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := rs.Consume(ctx, tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RequestStore.Consume() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if payload != tt.wantPayload {
+				t.Errorf("RequestStore.Consume() payload = %v, want %v", payload, tt.wantPayload)
+			}
+		})
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestRequestStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		solve   bool
+		advance time.Duration
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "Unsolved within ttl",
+			solve:   false,
+			advance: 30 * time.Second,
+			want:    "payload",
+			wantErr: nil,
+		},
+		{
+			name:    "Unsolved after ttl",
+			solve:   false,
+			advance: time.Minute + time.Second,
+			want:    "",
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "Solved within retention",
+			solve:   true,
+			advance: 4 * time.Minute,
+			want:    "",
+			wantErr: ErrAlreadySolved,
+		},
+		{
+			name:    "Solved after retention",
+			solve:   true,
+			advance: 5*time.Minute + time.Second,
+			want:    "",
+			wantErr: ErrExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+			rs := NewRequestStore(1, func(string) uint32 { return 0 }, time.Minute, 5*time.Minute)
+			rs.now = clock.Now
+
+			rs.Add(ctx, "key", "payload")
+			if tt.solve {
+				if _, err := rs.Consume(ctx, "key"); err != nil {
+					t.Fatalf("RequestStore.Consume() error = %v", err)
+				}
+			}
+			clock.Advance(tt.advance)
+
+			got, err := rs.Get(ctx, "key")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RequestStore.Get() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RequestStore.Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestStore_Consume_expired(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	rs := NewRequestStore(1, func(string) uint32 { return 0 }, time.Minute, time.Minute)
+	rs.now = clock.Now
+
+	rs.Add(ctx, "key", "")
+	clock.Advance(2 * time.Minute)
+
+	if _, err := rs.Consume(ctx, "key"); !errors.Is(err, ErrExpired) {
+		t.Errorf("RequestStore.Consume() error = %v, want %v", err, ErrExpired)
+	}
+}
+
+func TestRequestStore_Sweep(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	rs := NewRequestStore(8, func(in string) uint32 { return HashShard([]byte(in)) % 8 }, time.Minute, 3*time.Minute)
+	rs.now = clock.Now
+
+	rs.Add(ctx, "outdated", "")
+	rs.Add(ctx, "solved", "")
+	if _, err := rs.Consume(ctx, "solved"); err != nil {
+		t.Fatalf("RequestStore.Consume() error = %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+	rs.Add(ctx, "fresh", "")
+
+	if evicted := rs.Sweep(); evicted != 1 {
+		t.Errorf("RequestStore.Sweep() = %v, want %v", evicted, 1)
+	}
+	if _, err := rs.Get(ctx, "outdated"); err == nil {
+		t.Errorf("RequestStore.Get() expected evicted request to be missing")
+	}
+	if _, err := rs.Get(ctx, "fresh"); err != nil {
+		t.Errorf("RequestStore.Get() error = %v", err)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if evicted := rs.Sweep(); evicted != 2 {
+		t.Errorf("RequestStore.Sweep() = %v, want %v", evicted, 2)
+	}
+}
+
+func benchmarkStore(b *testing.B, shardsCnt int, keys []string) *RequestStore {
+	b.Helper()
+
+	rs := NewRequestStore(shardsCnt, func(in string) uint32 {
+		return HashShard([]byte(in)) % uint32(shardsCnt)
+	}, time.Hour, time.Hour)
+	for _, key := range keys {
+		rs.Add(context.Background(), key, "")
+	}
+	return rs
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = GenUID()
+	}
+	return keys
+}
+
+var benchmarkShards = []int{1, 8, 64}
+
+func BenchmarkRequestStore_Add(b *testing.B) {
+	keys := benchmarkKeys(1 << 16)
+
+	for _, shardsCnt := range benchmarkShards {
+		b.Run(fmt.Sprintf("shards=%d", shardsCnt), func(b *testing.B) {
+			ctx := context.Background()
+			rs := benchmarkStore(b, shardsCnt, nil)
+			var seed atomic.Uint64
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				// every goroutine walks the keys from its own offset to avoid a shared counter
+				i := seed.Add(uint64(len(keys)) / 64)
+				for pb.Next() {
+					i++
+					rs.Add(ctx, keys[i%uint64(len(keys))], "")
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkRequestStore_Get(b *testing.B) {
+	keys := benchmarkKeys(1 << 16)
+
+	for _, shardsCnt := range benchmarkShards {
+		b.Run(fmt.Sprintf("shards=%d", shardsCnt), func(b *testing.B) {
+			ctx := context.Background()
+			rs := benchmarkStore(b, shardsCnt, keys)
+			var seed atomic.Uint64
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				// every goroutine walks the keys from its own offset to avoid a shared counter
+				i := seed.Add(uint64(len(keys)) / 64)
+				for pb.Next() {
+					i++
+					_, _ = rs.Get(ctx, keys[i%uint64(len(keys))])
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkRequestStore_Consume(b *testing.B) {
+	keys := benchmarkKeys(1 << 16)
+
+	for _, shardsCnt := range benchmarkShards {
+		b.Run(fmt.Sprintf("shards=%d", shardsCnt), func(b *testing.B) {
+			ctx := context.Background()
+			rs := benchmarkStore(b, shardsCnt, keys)
+			var seed atomic.Uint64
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				// every goroutine walks the keys from its own offset to avoid a shared counter
+				i := seed.Add(uint64(len(keys)) / 64)
+				for pb.Next() {
+					i++
+					_, _ = rs.Consume(ctx, keys[i%uint64(len(keys))])
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkRequestStore_Mixed(b *testing.B) {
+	keys := benchmarkKeys(1 << 16)
+
+	for _, shardsCnt := range benchmarkShards {
+		b.Run(fmt.Sprintf("shards=%d", shardsCnt), func(b *testing.B) {
+			ctx := context.Background()
+			rs := benchmarkStore(b, shardsCnt, keys)
+			var seed atomic.Uint64
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				// every goroutine walks the keys from its own offset to avoid a shared counter
+				i := seed.Add(uint64(len(keys)) / 64)
+				for pb.Next() {
+					i++
+					key := keys[i%uint64(len(keys))]
+					switch i % 4 {
+					case 0:
+						rs.Add(ctx, key, "")
+					case 1:
+						_, _ = rs.Consume(ctx, key)
+					default:
+						_, _ = rs.Get(ctx, key)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestRequestStore_Stats(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Now()}
+	rs := NewRequestStore(8, func(in string) uint32 { return HashShard([]byte(in)) % 8 }, time.Minute, 5*time.Minute)
+	rs.now = clock.Now
+
+	rs.Add(ctx, "expired", "")
+	clock.Advance(2 * time.Minute)
+	rs.Add(ctx, "pending", "")
+	rs.Add(ctx, "solved", "")
+	if _, err := rs.Consume(ctx, "solved"); err != nil {
+		t.Fatalf("RequestStore.Consume() error = %v", err)
+	}
+
+	stats, err := rs.Stats(ctx)
+	if err != nil {
+		t.Fatalf("RequestStore.Stats() error = %v", err)
+	}
+	if want := (RequestStats{Outstanding: 1, Solved: 1}); stats != want {
+		t.Errorf("RequestStore.Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestRequestStore_sizeMetric(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Now()}
+	rs := NewRequestStore(4, func(in string) uint32 { return HashShard([]byte(in)) % 4 }, time.Minute, time.Minute)
+	rs.now = clock.Now
+
+	size := func() float64 {
+		var total float64
+		for i := range rs.shards {
+			total += testutil.ToFloat64(metrics.RequestStoreEntries.WithLabelValues(strconv.Itoa(i)))
+		}
+		return total
+	}
+
+	for i := 0; i < 10; i++ {
+		rs.Add(ctx, fmt.Sprint("request", i), "")
+	}
+	if err := rs.AddSolved(ctx, "signed"); err != nil {
+		t.Fatalf("RequestStore.AddSolved() error = %v", err)
+	}
+	if got := size(); got != 11 {
+		t.Errorf("request store size = %v, want 11", got)
+	}
+
+	clock.Advance(2 * time.Minute)
+	rs.Sweep()
+	if got := size(); got != 0 {
+		t.Errorf("request store size after sweep = %v, want 0", got)
+	}
+}
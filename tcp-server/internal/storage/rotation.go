@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+)
+
+type clientKey struct{}
+
+// WithClient attaches the client identity (IP or client ID) used by RotatingStorage.
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+func ClientFromContext(ctx context.Context) string {
+	client, _ := ctx.Value(clientKey{}).(string)
+	return client
+}
+
+// clientHistory is a ring of the last served quote ids with a set for lookups.
+type clientHistory struct {
+	client   string
+	order    []string
+	seen     map[string]struct{}
+	lastSeen time.Time
+}
+
+func (h *clientHistory) add(id string, size int) {
+	h.order = append(h.order, id)
+	h.seen[id] = struct{}{}
+	if len(h.order) > size {
+		delete(h.seen, h.order[0])
+		h.order = h.order[1:]
+	}
+}
+
+// RotatingStorage serves every client quotes it hasn't seen recently. For each client
+// it remembers up to historySize served ids, forgets clients idle for longer than ttl,
+// and starts over once every matching quote has been served. At most maxClients are
+// remembered, the least recently served one is forgotten to make room for a new one.
+type RotatingStorage struct {
+	storage     *Storage
+	historySize int
+	maxClients  int
+	ttl         time.Duration
+
+	mu      sync.Mutex
+	clients map[string]*list.Element
+	recent  *list.List // of *clientHistory, the most recently served first
+	rand    *rand.Rand
+	now     func() time.Time
+}
+
+func NewRotatingStorage(storage *Storage, historySize int, maxClients int, ttl time.Duration, src rand.Source) *RotatingStorage {
+	return &RotatingStorage{
+		storage:     storage,
+		historySize: historySize,
+		maxClients:  maxClients,
+		ttl:         ttl,
+		clients:     make(map[string]*list.Element),
+		recent:      list.New(),
+		rand:        rand.New(src),
+		now:         time.Now,
+	}
+}
+
+func (rs *RotatingStorage) GetRandomQuote(ctx context.Context, filter model.QuoteFilter) (model.Quote, error) {
+	client := ClientFromContext(ctx)
+	if client == "" {
+		return rs.storage.GetRandomQuote(ctx, filter)
+	}
+
+	var candidates []model.Quote
+	for _, q := range rs.storage.Quotes() {
+		if filter.Match(q) {
+			candidates = append(candidates, q)
+		}
+	}
+	if len(candidates) == 0 {
+		return model.Quote{}, ErrNoMatchingQuote
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := rs.now()
+	history := rs.history(client, now)
+
+	unseen := make([]model.Quote, 0, len(candidates))
+	for _, q := range candidates {
+		if _, ok := history.seen[q.ID]; !ok {
+			unseen = append(unseen, q)
+		}
+	}
+	if len(unseen) == 0 {
+		// the client has seen every matching quote: start over, but never repeat the last one
+		last := history.order[len(history.order)-1]
+		for _, q := range candidates {
+			if q.ID != last || len(candidates) == 1 {
+				unseen = append(unseen, q)
+			}
+		}
+		history.order = history.order[:0]
+		history.seen = make(map[string]struct{})
+	}
+
+	picked := unseen[rs.rand.Intn(len(unseen))]
+	history.add(picked.ID, rs.historySize)
+
+	return picked, nil
+}
+
+// history returns the history of the client, starting a new one if the client is unknown
+// or was idle for longer than ttl, and marks it the most recently served.
+func (rs *RotatingStorage) history(client string, now time.Time) *clientHistory {
+	if elem, ok := rs.clients[client]; ok {
+		history := elem.Value.(*clientHistory)
+		if now.Sub(history.lastSeen) > rs.ttl {
+			history.order = history.order[:0]
+			history.seen = make(map[string]struct{})
+		}
+		history.lastSeen = now
+		rs.recent.MoveToFront(elem)
+		return history
+	}
+
+	for len(rs.clients) >= rs.maxClients && rs.recent.Len() > 0 {
+		rs.remove(rs.recent.Back())
+	}
+	history := &clientHistory{client: client, seen: make(map[string]struct{}), lastSeen: now}
+	rs.clients[client] = rs.recent.PushFront(history)
+	return history
+}
+
+func (rs *RotatingStorage) remove(elem *list.Element) {
+	rs.recent.Remove(elem)
+	delete(rs.clients, elem.Value.(*clientHistory).client)
+}
+
+// GetDailyQuote is the same for every client and doesn't count towards the history.
+func (rs *RotatingStorage) GetDailyQuote(ctx context.Context, day string) (model.Quote, error) {
+	return rs.storage.GetDailyQuote(ctx, day)
+}
+
+// RunSweeper forgets idle clients every interval until ctx is done.
+func (rs *RotatingStorage) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if evicted := rs.Sweep(); evicted > 0 {
+				config.Logger.Debugf("Forgot quote history of %d idle clients", evicted)
+			}
+		}
+	}
+}
+
+// Sweep removes clients idle for longer than ttl and returns how many were removed.
+// Clients are ordered by when they were last served, so it stops at the first active one.
+func (rs *RotatingStorage) Sweep() int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := rs.now()
+	evicted := 0
+	for elem := rs.recent.Back(); elem != nil && now.Sub(elem.Value.(*clientHistory).lastSeen) > rs.ttl; elem = rs.recent.Back() {
+		rs.remove(elem)
+		evicted++
+	}
+	return evicted
+}
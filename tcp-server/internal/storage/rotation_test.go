@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+)
+
+func testCorpus(n int) []model.Quote {
+	quotes := make([]model.Quote, 0, n)
+	for i := 0; i < n; i++ {
+		tag := "even"
+		if i%2 == 1 {
+			tag = "odd"
+		}
+		quotes = append(quotes, model.Quote{ID: fmt.Sprint(i), Text: fmt.Sprint("quote ", i), Tags: []string{tag}})
+	}
+	return quotes
+}
+
+func TestRotatingStorage_GetRandomQuote(t *testing.T) {
+	t.Parallel()
+
+	rs := NewRotatingStorage(New(testCorpus(10)), 100, 1000, time.Hour, rand.NewSource(1))
+	ctx := WithClient(context.Background(), "10.0.0.1")
+
+	// every quote is served once before any repeats, and the cycle boundary never repeats
+	var last string
+	for cycle := 0; cycle < 3; cycle++ {
+		seen := make(map[string]bool)
+		for i := 0; i < 10; i++ {
+			q, err := rs.GetRandomQuote(ctx, model.QuoteFilter{})
+			if err != nil {
+				t.Fatalf("RotatingStorage.GetRandomQuote() error = %v", err)
+			}
+			if seen[q.ID] {
+				t.Fatalf("RotatingStorage.GetRandomQuote() repeated quote %s in cycle %d", q.ID, cycle)
+			}
+			if q.ID == last {
+				t.Fatalf("RotatingStorage.GetRandomQuote() served quote %s twice in a row", q.ID)
+			}
+			seen[q.ID] = true
+			last = q.ID
+		}
+	}
+}
+
+func TestRotatingStorage_deterministic(t *testing.T) {
+	t.Parallel()
+
+	sequence := func() []string {
+		rs := NewRotatingStorage(New(testCorpus(10)), 100, 1000, time.Hour, rand.NewSource(42))
+		ctx := WithClient(context.Background(), "client")
+
+		var ids []string
+		for i := 0; i < 25; i++ {
+			q, err := rs.GetRandomQuote(ctx, model.QuoteFilter{})
+			if err != nil {
+				t.Fatalf("RotatingStorage.GetRandomQuote() error = %v", err)
+			}
+			ids = append(ids, q.ID)
+		}
+		return ids
+	}
+
+	first, second := sequence(), sequence()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("RotatingStorage.GetRandomQuote() sequences differ at %d: %v and %v", i, first, second)
+		}
+	}
+}
+
+func TestRotatingStorage_perClient(t *testing.T) {
+	t.Parallel()
+
+	rs := NewRotatingStorage(New(testCorpus(2)), 100, 1000, time.Hour, rand.NewSource(1))
+
+	first, err := rs.GetRandomQuote(WithClient(context.Background(), "a"), model.QuoteFilter{})
+	if err != nil {
+		t.Fatalf("RotatingStorage.GetRandomQuote() error = %v", err)
+	}
+	// another client keeps its own history, so after "b" consumes the corpus "a" still gets the other quote
+	for i := 0; i < 2; i++ {
+		if _, err := rs.GetRandomQuote(WithClient(context.Background(), "b"), model.QuoteFilter{}); err != nil {
+			t.Fatalf("RotatingStorage.GetRandomQuote() error = %v", err)
+		}
+	}
+	second, err := rs.GetRandomQuote(WithClient(context.Background(), "a"), model.QuoteFilter{})
+	if err != nil {
+		t.Fatalf("RotatingStorage.GetRandomQuote() error = %v", err)
+	}
+	if first.ID == second.ID {
+		t.Errorf("RotatingStorage.GetRandomQuote() served %s twice to the same client", first.ID)
+	}
+}
+
+func TestRotatingStorage_filter(t *testing.T) {
+	t.Parallel()
+
+	rs := NewRotatingStorage(New(testCorpus(10)), 100, 1000, time.Hour, rand.NewSource(1))
+	ctx := WithClient(context.Background(), "client")
+
+	seen := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		q, err := rs.GetRandomQuote(ctx, model.QuoteFilter{Tags: []string{"odd"}})
+		if err != nil {
+			t.Fatalf("RotatingStorage.GetRandomQuote() error = %v", err)
+		}
+		if seen[q.ID] || q.Tags[0] != "odd" {
+			t.Fatalf("RotatingStorage.GetRandomQuote() = %v, want unseen odd quote", q)
+		}
+		seen[q.ID] = true
+	}
+
+	if _, err := rs.GetRandomQuote(ctx, model.QuoteFilter{Author: "Nobody"}); !errors.Is(err, ErrNoMatchingQuote) {
+		t.Errorf("RotatingStorage.GetRandomQuote() error = %v, want %v", err, ErrNoMatchingQuote)
+	}
+}
+
+func TestRotatingStorage_boundedHistory(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{now: time.Now()}
+	rs := NewRotatingStorage(New(testCorpus(10)), 3, 1000, time.Minute, rand.NewSource(1))
+	rs.now = clock.Now
+	ctx := WithClient(context.Background(), "client")
+
+	// with a history of 3 only the last 3 quotes are excluded
+	var served []string
+	for i := 0; i < 20; i++ {
+		q, err := rs.GetRandomQuote(ctx, model.QuoteFilter{})
+		if err != nil {
+			t.Fatalf("RotatingStorage.GetRandomQuote() error = %v", err)
+		}
+		for _, id := range served[max(0, len(served)-3):] {
+			if id == q.ID {
+				t.Fatalf("RotatingStorage.GetRandomQuote() repeated %s within the last 3 quotes %v", q.ID, served)
+			}
+		}
+		served = append(served, q.ID)
+	}
+	if got := len(rs.clients["client"].Value.(*clientHistory).order); got != 3 {
+		t.Errorf("history length = %d, want 3", got)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if evicted := rs.Sweep(); evicted != 1 {
+		t.Errorf("RotatingStorage.Sweep() = %d, want 1", evicted)
+	}
+}
+
+func TestRotatingStorage_maxClients(t *testing.T) {
+	t.Parallel()
+
+	rs := NewRotatingStorage(New(testCorpus(10)), 100, 2, time.Hour, rand.NewSource(1))
+	for _, client := range []string{"a", "b", "a", "c"} {
+		if _, err := rs.GetRandomQuote(WithClient(context.Background(), client), model.QuoteFilter{}); err != nil {
+			t.Fatalf("RotatingStorage.GetRandomQuote() error = %v", err)
+		}
+	}
+
+	// b is the least recently served client, so c takes its place
+	if len(rs.clients) != 2 || rs.clients["a"] == nil || rs.clients["c"] == nil {
+		t.Errorf("RotatingStorage tracks %d clients, want a and c", len(rs.clients))
+	}
+	if rs.recent.Len() != len(rs.clients) {
+		t.Errorf("RotatingStorage recency list has %d clients, map has %d", rs.recent.Len(), len(rs.clients))
+	}
+}
+
+func TestRotatingStorage_noClient(t *testing.T) {
+	t.Parallel()
+
+	rs := NewRotatingStorage(New(testCorpus(3)), 100, 1000, time.Hour, rand.NewSource(1))
+	if _, err := rs.GetRandomQuote(context.Background(), model.QuoteFilter{}); err != nil {
+		t.Fatalf("RotatingStorage.GetRandomQuote() error = %v", err)
+	}
+	if len(rs.clients) != 0 {
+		t.Errorf("RotatingStorage tracked %d clients without identity, want 0", len(rs.clients))
+	}
+}
@@ -0,0 +1,546 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations are applied in order on startup, the number of applied ones
+// is kept in PRAGMA user_version. Never edit an applied migration, add a new one.
+var sqliteMigrations = []string{
+	`CREATE TABLE quotes (
+		seq      INTEGER PRIMARY KEY,
+		id       TEXT NOT NULL UNIQUE,
+		text     TEXT NOT NULL,
+		text_key TEXT NOT NULL UNIQUE,
+		author   TEXT NOT NULL DEFAULT '',
+		source   TEXT NOT NULL DEFAULT '',
+		language TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX quotes_author ON quotes (author COLLATE NOCASE);
+	CREATE INDEX quotes_language ON quotes (language COLLATE NOCASE);
+	CREATE TABLE categories (
+		id   INTEGER PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE COLLATE NOCASE
+	);
+	CREATE TABLE quote_categories (
+		quote_seq   INTEGER NOT NULL REFERENCES quotes (seq) ON DELETE CASCADE,
+		category_id INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+		PRIMARY KEY (quote_seq, category_id)
+	);
+	CREATE INDEX quote_categories_category ON quote_categories (category_id);`,
+
+	`CREATE TABLE quote_usage (
+		quote_seq      INTEGER PRIMARY KEY REFERENCES quotes (seq) ON DELETE CASCADE,
+		served         INTEGER NOT NULL DEFAULT 0,
+		last_served_at INTEGER NOT NULL
+	);`,
+}
+
+const quoteColumns = "q.seq, q.id, q.text, q.author, q.source, q.language"
+
+// usageFlushInterval is how often the usage counted in memory is written to the database.
+const usageFlushInterval = 5 * time.Second
+
+// SQLiteStorage keeps quotes, their categories (tags) and usage counters in an
+// embedded SQLite database. Quotes are picked in SQL, without loading the corpus.
+// Serving a quote doesn't write, the usage is counted in memory and flushed in batches.
+type SQLiteStorage struct {
+	db  *sql.DB
+	now func() time.Time
+
+	mu      sync.Mutex
+	pending map[int64]pendingUsage // by quote seq
+}
+
+type pendingUsage struct {
+	served     int64
+	lastServed int64 // unix milliseconds
+}
+
+// NewSQLiteStorage opens (or creates) the database at path and migrates its schema.
+func NewSQLiteStorage(path string) (*SQLiteStorage, error) {
+	dsn := "file:" + path + "?" + url.Values{
+		"_pragma": {"busy_timeout(5000)", "journal_mode(WAL)", "foreign_keys(1)"},
+	}.Encode()
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := &SQLiteStorage{db: db, now: time.Now, pending: make(map[int64]pendingUsage)}
+	if err := ss.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating %s: %w", path, err)
+	}
+	return ss, nil
+}
+
+// Close writes the usage counted since the last flush and closes the database.
+func (ss *SQLiteStorage) Close() error {
+	if err := ss.FlushUsage(context.Background()); err != nil {
+		config.Logger.Errorf("Failed to flush quote usage: %v", err)
+	}
+	return ss.db.Close()
+}
+
+func (ss *SQLiteStorage) migrate() error {
+	var version int
+	if err := ss.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("schema version %d is newer than supported %d", version, len(sqliteMigrations))
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := ss.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		config.Logger.Infof("Quote storage migrated to schema version %d", i+1)
+	}
+	return nil
+}
+
+// GetRandomQuote counts the matching quotes and takes the one at a random offset,
+// so the pick is uniform and filters are resolved by the indexes.
+func (ss *SQLiteStorage) GetRandomQuote(ctx context.Context, filter model.QuoteFilter) (model.Quote, error) {
+	where, args := filterClause(filter)
+
+	tx, err := ss.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return model.Quote{}, err
+	}
+	defer tx.Rollback()
+
+	var matched int64
+	if err := tx.QueryRowContext(ctx, "SELECT count(*) FROM quotes q WHERE "+where, args...).Scan(&matched); err != nil {
+		return model.Quote{}, err
+	}
+	if matched == 0 {
+		return model.Quote{}, ErrNoMatchingQuote
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT "+quoteColumns+" FROM quotes q WHERE "+where+" ORDER BY q.seq LIMIT 1 OFFSET ?",
+		append(args, rand.Int63n(matched))...)
+	seq, q, err := scanQuote(ctx, tx, row)
+	if err != nil {
+		return model.Quote{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return model.Quote{}, err
+	}
+
+	ss.countUsage(seq)
+	return q, nil
+}
+
+// GetDailyQuote picks the same quote as Storage.GetDailyQuote for the same corpus.
+func (ss *SQLiteStorage) GetDailyQuote(ctx context.Context, day string) (model.Quote, error) {
+	rows, err := ss.db.QueryContext(ctx, "SELECT id FROM quotes")
+	if err != nil {
+		return model.Quote{}, err
+	}
+	defer rows.Close()
+
+	var picked string
+	var best uint64
+	for found := false; rows.Next(); found = true {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return model.Quote{}, err
+		}
+		if score := dailyScore(day, id); !found || score > best {
+			picked, best = id, score
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return model.Quote{}, err
+	}
+	if picked == "" {
+		return model.Quote{}, ErrNoMatchingQuote
+	}
+
+	seq, q, err := ss.getQuote(ctx, ss.db, picked)
+	if err != nil {
+		return model.Quote{}, err
+	}
+	ss.countUsage(seq)
+	return q, nil
+}
+
+// GetQuote returns the quote with the id.
+func (ss *SQLiteStorage) GetQuote(ctx context.Context, id string) (model.Quote, error) {
+	_, q, err := ss.getQuote(ctx, ss.db, id)
+	return q, err
+}
+
+// ListQuotes returns every quote in the order they were added.
+func (ss *SQLiteStorage) ListQuotes(ctx context.Context) ([]model.Quote, error) {
+	rows, err := ss.db.QueryContext(ctx, `SELECT q.seq, c.name FROM quote_categories qc
+		JOIN quotes q ON q.seq = qc.quote_seq
+		JOIN categories c ON c.id = qc.category_id
+		ORDER BY qc.rowid`)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[int64][]string)
+	for rows.Next() {
+		var seq int64
+		var name string
+		if err := rows.Scan(&seq, &name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tags[seq] = append(tags[seq], name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = ss.db.QueryContext(ctx, "SELECT "+quoteColumns+" FROM quotes q ORDER BY q.seq")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotes []model.Quote
+	for rows.Next() {
+		var seq int64
+		var q model.Quote
+		if err := rows.Scan(&seq, &q.ID, &q.Text, &q.Author, &q.Source, &q.Language); err != nil {
+			return nil, err
+		}
+		q.Tags = tags[seq]
+		quotes = append(quotes, q)
+	}
+	return quotes, rows.Err()
+}
+
+func (ss *SQLiteStorage) Count(ctx context.Context) (int, error) {
+	var count int
+	err := ss.db.QueryRowContext(ctx, "SELECT count(*) FROM quotes").Scan(&count)
+	return count, err
+}
+
+// AddQuote validates the quote like LoadQuotes does and stores it. The stored quote,
+// with the id generated from its text if it had none, is returned.
+func (ss *SQLiteStorage) AddQuote(ctx context.Context, q model.Quote) (model.Quote, error) {
+	q, reason := normalizeQuote(q)
+	if reason != "" {
+		return model.Quote{}, fmt.Errorf("%w: %s", ErrInvalidQuote, reason)
+	}
+
+	err := ss.inTx(ctx, func(tx *sql.Tx) error {
+		return insertQuote(ctx, tx, q)
+	})
+	if err != nil {
+		return model.Quote{}, err
+	}
+	return q, nil
+}
+
+// Import adds quotes in a single transaction, it is used to seed an empty database.
+func (ss *SQLiteStorage) Import(ctx context.Context, quotes []model.Quote) error {
+	return ss.inTx(ctx, func(tx *sql.Tx) error {
+		for _, q := range quotes {
+			q, reason := normalizeQuote(q)
+			if reason != "" {
+				return fmt.Errorf("%w %q: %s", ErrInvalidQuote, q.ID, reason)
+			}
+			if err := insertQuote(ctx, tx, q); err != nil {
+				return fmt.Errorf("quote %q: %w", q.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateQuote replaces the quote with the same id, keeping its usage counters.
+// The stored quote is returned.
+func (ss *SQLiteStorage) UpdateQuote(ctx context.Context, q model.Quote) (model.Quote, error) {
+	if strings.TrimSpace(q.ID) == "" {
+		return model.Quote{}, fmt.Errorf("%w: empty id", ErrInvalidQuote)
+	}
+	q, reason := normalizeQuote(q)
+	if reason != "" {
+		return model.Quote{}, fmt.Errorf("%w: %s", ErrInvalidQuote, reason)
+	}
+
+	err := ss.inTx(ctx, func(tx *sql.Tx) error {
+		var seq int64
+		err := tx.QueryRowContext(ctx, "SELECT seq FROM quotes WHERE id = ?", q.ID).Scan(&seq)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrQuoteNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var taken bool
+		err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM quotes WHERE text_key = ? AND seq != ?)",
+			strings.ToLower(q.Text), seq).Scan(&taken)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrQuoteExists
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE quotes SET text = ?, text_key = ?, author = ?, source = ?, language = ? WHERE seq = ?",
+			q.Text, strings.ToLower(q.Text), q.Author, q.Source, q.Language, seq)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM quote_categories WHERE quote_seq = ?", seq); err != nil {
+			return err
+		}
+		return setCategories(ctx, tx, seq, q.Tags)
+	})
+	if err != nil {
+		return model.Quote{}, err
+	}
+	return q, nil
+}
+
+func (ss *SQLiteStorage) DeleteQuote(ctx context.Context, id string) error {
+	res, err := ss.db.ExecContext(ctx, "DELETE FROM quotes WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrQuoteNotFound
+	}
+	return nil
+}
+
+// Usage returns the usage counters of the quote, zero if it was never served.
+// The usage counted since the last flush is written first.
+func (ss *SQLiteStorage) Usage(ctx context.Context, id string) (QuoteUsage, error) {
+	if err := ss.FlushUsage(ctx); err != nil {
+		return QuoteUsage{}, err
+	}
+
+	var served sql.NullInt64
+	var lastServed sql.NullInt64
+	err := ss.db.QueryRowContext(ctx, `SELECT u.served, u.last_served_at FROM quotes q
+		LEFT JOIN quote_usage u ON u.quote_seq = q.seq WHERE q.id = ?`, id).Scan(&served, &lastServed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return QuoteUsage{}, ErrQuoteNotFound
+	}
+	if err != nil {
+		return QuoteUsage{}, err
+	}
+	if !served.Valid {
+		return QuoteUsage{}, nil
+	}
+	return QuoteUsage{Served: served.Int64, LastServed: time.UnixMilli(lastServed.Int64)}, nil
+}
+
+// RunUsageFlusher writes the usage counted in memory every usageFlushInterval until ctx is done.
+// Close writes what is left.
+func (ss *SQLiteStorage) RunUsageFlusher(ctx context.Context) {
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ss.FlushUsage(ctx); err != nil {
+				config.Logger.Errorf("Failed to flush quote usage: %v", err)
+			}
+		}
+	}
+}
+
+// FlushUsage writes the usage counted since the last flush in a single transaction.
+// Counters of quotes deleted in between are dropped, on failure the counts are kept for the next flush.
+func (ss *SQLiteStorage) FlushUsage(ctx context.Context) error {
+	ss.mu.Lock()
+	pending := ss.pending
+	ss.pending = make(map[int64]pendingUsage)
+	ss.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := ss.inTx(ctx, func(tx *sql.Tx) error {
+		for seq, u := range pending {
+			_, err := tx.ExecContext(ctx, `INSERT INTO quote_usage (quote_seq, served, last_served_at)
+				SELECT seq, ?, ? FROM quotes WHERE seq = ?
+				ON CONFLICT (quote_seq) DO UPDATE SET served = served + excluded.served,
+					last_served_at = max(last_served_at, excluded.last_served_at)`,
+				u.served, u.lastServed, seq)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ss.mu.Lock()
+		for seq, u := range pending {
+			ss.pending[seq] = u.merge(ss.pending[seq])
+		}
+		ss.mu.Unlock()
+	}
+	return err
+}
+
+// countUsage only counts in memory, serving a quote must not wait for a database write.
+func (ss *SQLiteStorage) countUsage(seq int64) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.pending[seq] = ss.pending[seq].merge(pendingUsage{served: 1, lastServed: ss.now().UnixMilli()})
+}
+
+func (u pendingUsage) merge(other pendingUsage) pendingUsage {
+	u.served += other.served
+	u.lastServed = max(u.lastServed, other.lastServed)
+	return u
+}
+
+func (ss *SQLiteStorage) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := ss.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (ss *SQLiteStorage) getQuote(ctx context.Context, db queryer, id string) (int64, model.Quote, error) {
+	row := db.QueryRowContext(ctx, "SELECT "+quoteColumns+" FROM quotes q WHERE q.id = ?", id)
+	seq, q, err := scanQuote(ctx, db, row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, model.Quote{}, ErrQuoteNotFound
+	}
+	return seq, q, err
+}
+
+func scanQuote(ctx context.Context, db queryer, row *sql.Row) (int64, model.Quote, error) {
+	var seq int64
+	var q model.Quote
+	if err := row.Scan(&seq, &q.ID, &q.Text, &q.Author, &q.Source, &q.Language); err != nil {
+		return 0, model.Quote{}, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT c.name FROM quote_categories qc
+		JOIN categories c ON c.id = qc.category_id
+		WHERE qc.quote_seq = ? ORDER BY qc.rowid`, seq)
+	if err != nil {
+		return 0, model.Quote{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return 0, model.Quote{}, err
+		}
+		q.Tags = append(q.Tags, name)
+	}
+	return seq, q, rows.Err()
+}
+
+func insertQuote(ctx context.Context, tx *sql.Tx, q model.Quote) error {
+	var taken bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM quotes WHERE id = ? OR text_key = ?)",
+		q.ID, strings.ToLower(q.Text)).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrQuoteExists
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO quotes (id, text, text_key, author, source, language) VALUES (?, ?, ?, ?, ?, ?)",
+		q.ID, q.Text, strings.ToLower(q.Text), q.Author, q.Source, q.Language)
+	if err != nil {
+		return err
+	}
+	seq, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	return setCategories(ctx, tx, seq, q.Tags)
+}
+
+// setCategories links the quote to its tags, creating the missing categories.
+func setCategories(ctx context.Context, tx *sql.Tx, seq int64, tags []string) error {
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO categories (name) VALUES (?)", tag); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO quote_categories (quote_seq, category_id)
+			SELECT ?, id FROM categories WHERE name = ?`, seq, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterClause builds the WHERE condition for the filter. NOCASE matches only ASCII
+// letters case-insensitively, unlike QuoteFilter.Match.
+func filterClause(filter model.QuoteFilter) (string, []any) {
+	conds := []string{"1"}
+	var args []any
+	if filter.Author != "" {
+		conds = append(conds, "q.author = ? COLLATE NOCASE")
+		args = append(args, filter.Author)
+	}
+	if filter.Language != "" {
+		conds = append(conds, "q.language = ? COLLATE NOCASE")
+		args = append(args, filter.Language)
+	}
+	for _, tag := range filter.Tags {
+		conds = append(conds, `EXISTS (SELECT 1 FROM quote_categories qc
+			JOIN categories c ON c.id = qc.category_id
+			WHERE qc.quote_seq = q.seq AND c.name = ?)`)
+		args = append(args, tag)
+	}
+	return strings.Join(conds, " AND "), args
+}
@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/config"
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+)
+
+func openSQLiteStorage(t *testing.T, path string) *SQLiteStorage {
+	t.Helper()
+
+	ss, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { ss.Close() })
+	return ss
+}
+
+func TestSQLiteStorage_GetRandomQuote(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	ss := openSQLiteStorage(t, filepath.Join(t.TempDir(), "quotes.db"))
+
+	err := ss.Import(ctx, []model.Quote{
+		{ID: "1", Text: "Luck is what happens when preparation meets opportunity", Author: "Seneca", Language: "en", Tags: []string{"luck"}},
+		{ID: "2", Text: "Vivere est cogitare", Author: "Cicero", Language: "la", Tags: []string{"life", "thought"}},
+		{ID: "3", Text: "While we wait for life, life passes", Author: "Seneca", Language: "en", Tags: []string{"Life", "time"}},
+	})
+	if err != nil {
+		t.Fatalf("SQLiteStorage.Import() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		filter  model.QuoteFilter
+		wantIDs []string
+		wantErr error
+	}{
+		{
+			name:    "No filter",
+			filter:  model.QuoteFilter{},
+			wantIDs: []string{"1", "2", "3"},
+		},
+		{
+			name:    "Author",
+			filter:  model.QuoteFilter{Author: "seneca"},
+			wantIDs: []string{"1", "3"},
+		},
+		{
+			name:    "Language and tag",
+			filter:  model.QuoteFilter{Language: "EN", Tags: []string{"life"}},
+			wantIDs: []string{"3"},
+		},
+		{
+			name:    "All tags required",
+			filter:  model.QuoteFilter{Tags: []string{"LIFE", "thought"}},
+			wantIDs: []string{"2"},
+		},
+		{
+			name:    "No match",
+			filter:  model.QuoteFilter{Author: "Cicero", Language: "en"},
+			wantErr: ErrNoMatchingQuote,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := make(map[string]bool)
+			for i := 0; i < 100; i++ {
+				q, err := ss.GetRandomQuote(ctx, tt.filter)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SQLiteStorage.GetRandomQuote() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil {
+					return
+				}
+				seen[q.ID] = true
+			}
+
+			if len(seen) != len(tt.wantIDs) {
+				t.Errorf("SQLiteStorage.GetRandomQuote() returned %v, want %v", seen, tt.wantIDs)
+			}
+			for _, id := range tt.wantIDs {
+				if !seen[id] {
+					t.Errorf("SQLiteStorage.GetRandomQuote() never returned quote %s", id)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteStorage_GetDailyQuote(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	ss := openSQLiteStorage(t, filepath.Join(t.TempDir(), "quotes.db"))
+
+	corpus := testCorpus(10)
+	if err := ss.Import(ctx, corpus); err != nil {
+		t.Fatalf("SQLiteStorage.Import() error = %v", err)
+	}
+	memory := New(corpus)
+
+	for day := 1; day <= 10; day++ {
+		date := fmt.Sprintf("2024-04-%02d", day)
+
+		got, err := ss.GetDailyQuote(ctx, date)
+		if err != nil {
+			t.Fatalf("SQLiteStorage.GetDailyQuote() error = %v", err)
+		}
+		want, _ := memory.GetDailyQuote(ctx, date)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SQLiteStorage.GetDailyQuote(%s) = %v, want %v", date, got, want)
+		}
+	}
+}
+
+func TestSQLiteStorage_CRUD(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	ss := openSQLiteStorage(t, filepath.Join(t.TempDir(), "quotes.db"))
+
+	added, err := ss.AddQuote(ctx, model.Quote{Text: " Vivere est cogitare ", Author: "Cicero", Tags: []string{"life"}})
+	if err != nil {
+		t.Fatalf("SQLiteStorage.AddQuote() error = %v", err)
+	}
+	if added.ID != QuoteID("Vivere est cogitare") || added.Text != "Vivere est cogitare" {
+		t.Errorf("SQLiteStorage.AddQuote() = %v, want trimmed quote with generated id", added)
+	}
+
+	addTests := []struct {
+		name    string
+		quote   model.Quote
+		wantErr error
+	}{
+		{
+			name:    "Duplicate text",
+			quote:   model.Quote{ID: "other", Text: "VIVERE EST COGITARE"},
+			wantErr: ErrQuoteExists,
+		},
+		{
+			name:    "Duplicate id",
+			quote:   model.Quote{ID: added.ID, Text: "Something else"},
+			wantErr: ErrQuoteExists,
+		},
+		{
+			name:    "Empty text",
+			quote:   model.Quote{ID: "empty", Text: "  "},
+			wantErr: ErrInvalidQuote,
+		},
+	}
+	for _, tt := range addTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ss.AddQuote(ctx, tt.quote); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SQLiteStorage.AddQuote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	updated := model.Quote{ID: added.ID, Text: "Vivere est cogitare", Author: "Marcus Tullius Cicero", Language: "la", Tags: []string{"thought"}}
+	if got, err := ss.UpdateQuote(ctx, updated); err != nil || !reflect.DeepEqual(got, updated) {
+		t.Fatalf("SQLiteStorage.UpdateQuote() = %v, %v, want %v", got, err, updated)
+	}
+	if got, err := ss.GetQuote(ctx, added.ID); err != nil || !reflect.DeepEqual(got, updated) {
+		t.Errorf("SQLiteStorage.GetQuote() = %v, %v, want %v", got, err, updated)
+	}
+	if _, err := ss.UpdateQuote(ctx, model.Quote{ID: "unknown", Text: "text"}); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("SQLiteStorage.UpdateQuote() error = %v, want %v", err, ErrQuoteNotFound)
+	}
+
+	if _, err := ss.GetRandomQuote(ctx, model.QuoteFilter{Tags: []string{"life"}}); !errors.Is(err, ErrNoMatchingQuote) {
+		t.Errorf("SQLiteStorage.GetRandomQuote() error = %v, want %v after tags update", err, ErrNoMatchingQuote)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := ss.GetRandomQuote(ctx, model.QuoteFilter{Tags: []string{"thought"}}); err != nil {
+			t.Fatalf("SQLiteStorage.GetRandomQuote() error = %v", err)
+		}
+	}
+	if usage, err := ss.Usage(ctx, added.ID); err != nil || usage.Served != 3 {
+		t.Errorf("SQLiteStorage.Usage() = %+v, %v, want 3 served", usage, err)
+	}
+	if _, err := ss.GetRandomQuote(ctx, model.QuoteFilter{}); err != nil {
+		t.Fatalf("SQLiteStorage.GetRandomQuote() error = %v", err)
+	}
+
+	if err := ss.DeleteQuote(ctx, added.ID); err != nil {
+		t.Fatalf("SQLiteStorage.DeleteQuote() error = %v", err)
+	}
+	// the usage of a deleted quote is dropped instead of failing the batch
+	if err := ss.FlushUsage(ctx); err != nil {
+		t.Errorf("SQLiteStorage.FlushUsage() error = %v", err)
+	}
+	if err := ss.DeleteQuote(ctx, added.ID); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("SQLiteStorage.DeleteQuote() error = %v, want %v", err, ErrQuoteNotFound)
+	}
+	if quotes, err := ss.ListQuotes(ctx); err != nil || len(quotes) != 0 {
+		t.Errorf("SQLiteStorage.ListQuotes() = %v, %v, want none", quotes, err)
+	}
+}
+
+func TestSQLiteStorage_Reopen(t *testing.T) {
+	config.InitLogger()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "quotes.db")
+
+	ss, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	corpus := testCorpus(3)
+	if err := ss.Import(ctx, corpus); err != nil {
+		t.Fatalf("SQLiteStorage.Import() error = %v", err)
+	}
+	served, err := ss.GetDailyQuote(ctx, "2024-01-01")
+	if err != nil {
+		t.Fatalf("SQLiteStorage.GetDailyQuote() error = %v", err)
+	}
+	if err := ss.Close(); err != nil {
+		t.Fatalf("SQLiteStorage.Close() error = %v", err)
+	}
+
+	// migrations already applied are skipped and the data is kept
+	ss = openSQLiteStorage(t, path)
+	quotes, err := ss.ListQuotes(ctx)
+	if err != nil {
+		t.Fatalf("SQLiteStorage.ListQuotes() error = %v", err)
+	}
+	if !reflect.DeepEqual(quotes, corpus) {
+		t.Errorf("SQLiteStorage.ListQuotes() = %v, want %v", quotes, corpus)
+	}
+	// Close flushes the usage counted in memory
+	if usage, err := ss.Usage(ctx, served.ID); err != nil || usage.Served != 1 {
+		t.Errorf("SQLiteStorage.Usage() = %+v, %v, want 1 served", usage, err)
+	}
+
+	var version int
+	if err := ss.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil || version != len(sqliteMigrations) {
+		t.Errorf("schema version = %d, %v, want %d", version, err, len(sqliteMigrations))
+	}
+}
@@ -2,24 +2,227 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+	"github.com/spaolacci/murmur3"
+)
+
+var (
+	ErrNoMatchingQuote = errors.New("no matching quote")
+	ErrQuoteNotFound   = errors.New("quote not found")
+	ErrQuoteExists     = errors.New("quote already exists")
+	ErrInvalidQuote    = errors.New("invalid quote")
 )
 
 //go:generate mockery --name=Storageer --output=mocks --case=underscore
 type Storageer interface {
-	GetRandomWOW(ctx context.Context) string
+	GetRandomQuote(ctx context.Context, filter model.QuoteFilter) (model.Quote, error)
+	GetDailyQuote(ctx context.Context, day string) (model.Quote, error)
+}
+
+// QuoteAdmin manages the quote corpus at runtime.
+type QuoteAdmin interface {
+	ListQuotes(ctx context.Context) ([]model.Quote, error)
+	GetQuote(ctx context.Context, id string) (model.Quote, error)
+	AddQuote(ctx context.Context, q model.Quote) (model.Quote, error)
+	UpdateQuote(ctx context.Context, q model.Quote) (model.Quote, error)
+	DeleteQuote(ctx context.Context, id string) error
+}
+
+// UsageCounter reports how many times quotes have been served.
+type UsageCounter interface {
+	Usage(ctx context.Context, id string) (QuoteUsage, error)
 }
 
+// QuoteUsage tells how many times a quote has been served, LastServed is zero if it never was.
+type QuoteUsage struct {
+	Served     int64     `json:"served"`
+	LastServed time.Time `json:"last_served"`
+}
+
+// Storage keeps the quote corpus behind an atomic pointer, so Swap never blocks
+// or affects readers that already picked up the previous set.
 type Storage struct {
-	wow []string
+	wow atomic.Pointer[[]model.Quote]
+	mu  sync.Mutex // serializes writers
+}
+
+func New(wow []model.Quote) *Storage {
+	s := &Storage{}
+	s.Swap(wow)
+	return s
+}
+
+// GetRandomQuote picks a random quote matching filter, scanning the corpus once.
+func (ims *Storage) GetRandomQuote(ctx context.Context, filter model.QuoteFilter) (model.Quote, error) {
+	wow := *ims.wow.Load()
+	if len(wow) == 0 {
+		return model.Quote{}, ErrNoMatchingQuote
+	}
+	if filter.IsEmpty() {
+		return wow[rand.Intn(len(wow))], nil
+	}
+
+	// reservoir sampling keeps the pick uniform without collecting the matches
+	var picked model.Quote
+	matched := 0
+	for _, q := range wow {
+		if !filter.Match(q) {
+			continue
+		}
+		matched++
+		if rand.Intn(matched) == 0 {
+			picked = q
+		}
+	}
+	if matched == 0 {
+		return model.Quote{}, ErrNoMatchingQuote
+	}
+	return picked, nil
 }
 
-func New(wow []string) Storage {
-	return Storage{
-		wow: wow,
+// GetDailyQuote picks the quote of the given day. The quote with the highest hash of
+// day and quote id wins, so every replica with the same corpus picks the same quote
+// regardless of its order, and adding or removing a quote rarely changes the pick.
+func (ims *Storage) GetDailyQuote(ctx context.Context, day string) (model.Quote, error) {
+	wow := *ims.wow.Load()
+	if len(wow) == 0 {
+		return model.Quote{}, ErrNoMatchingQuote
 	}
+
+	var picked model.Quote
+	var best uint64
+	for i, q := range wow {
+		score := dailyScore(day, q.ID)
+		if i == 0 || score > best {
+			picked, best = q, score
+		}
+	}
+	return picked, nil
+}
+
+func dailyScore(day, id string) uint64 {
+	return murmur3.Sum64([]byte(day + "\x00" + id))
+}
+
+// Swap replaces the quote corpus.
+func (ims *Storage) Swap(wow []model.Quote) {
+	ims.mu.Lock()
+	defer ims.mu.Unlock()
+
+	ims.wow.Store(&wow)
+}
+
+// update replaces the corpus with the one fn builds from the current corpus,
+// without losing writes that happen in between.
+func (ims *Storage) update(fn func(wow []model.Quote) []model.Quote) {
+	ims.mu.Lock()
+	defer ims.mu.Unlock()
+
+	updated := fn(*ims.wow.Load())
+	ims.wow.Store(&updated)
+}
+
+func (ims *Storage) ListQuotes(_ context.Context) ([]model.Quote, error) {
+	wow := *ims.wow.Load()
+	return append([]model.Quote(nil), wow...), nil
+}
+
+// AddQuote validates the quote like LoadQuotes does and appends it to a copy of the corpus.
+func (ims *Storage) AddQuote(_ context.Context, q model.Quote) (model.Quote, error) {
+	q, reason := normalizeQuote(q)
+	if reason != "" {
+		return model.Quote{}, fmt.Errorf("%w: %s", ErrInvalidQuote, reason)
+	}
+
+	ims.mu.Lock()
+	defer ims.mu.Unlock()
+
+	wow := *ims.wow.Load()
+	for _, existing := range wow {
+		if existing.ID == q.ID || strings.EqualFold(existing.Text, q.Text) {
+			return model.Quote{}, ErrQuoteExists
+		}
+	}
+
+	updated := make([]model.Quote, 0, len(wow)+1)
+	updated = append(append(updated, wow...), q)
+	ims.wow.Store(&updated)
+	return q, nil
+}
+
+func (ims *Storage) GetQuote(_ context.Context, id string) (model.Quote, error) {
+	for _, q := range *ims.wow.Load() {
+		if q.ID == id {
+			return q, nil
+		}
+	}
+	return model.Quote{}, ErrQuoteNotFound
+}
+
+// UpdateQuote validates the quote like AddQuote and replaces the one with the same id
+// in a copy of the corpus.
+func (ims *Storage) UpdateQuote(_ context.Context, q model.Quote) (model.Quote, error) {
+	if strings.TrimSpace(q.ID) == "" {
+		return model.Quote{}, fmt.Errorf("%w: empty id", ErrInvalidQuote)
+	}
+	q, reason := normalizeQuote(q)
+	if reason != "" {
+		return model.Quote{}, fmt.Errorf("%w: %s", ErrInvalidQuote, reason)
+	}
+
+	ims.mu.Lock()
+	defer ims.mu.Unlock()
+
+	wow := *ims.wow.Load()
+	found := -1
+	for i, existing := range wow {
+		switch {
+		case existing.ID == q.ID:
+			found = i
+		case strings.EqualFold(existing.Text, q.Text):
+			return model.Quote{}, ErrQuoteExists
+		}
+	}
+	if found < 0 {
+		return model.Quote{}, ErrQuoteNotFound
+	}
+
+	updated := append([]model.Quote(nil), wow...)
+	updated[found] = q
+	ims.wow.Store(&updated)
+	return q, nil
+}
+
+func (ims *Storage) DeleteQuote(_ context.Context, id string) error {
+	ims.mu.Lock()
+	defer ims.mu.Unlock()
+
+	wow := *ims.wow.Load()
+	for i, q := range wow {
+		if q.ID != id {
+			continue
+		}
+		updated := make([]model.Quote, 0, len(wow)-1)
+		updated = append(append(updated, wow[:i]...), wow[i+1:]...)
+		ims.wow.Store(&updated)
+		return nil
+	}
+	return ErrQuoteNotFound
+}
+
+// Quotes returns the current corpus. The slice is shared and must not be modified.
+func (ims *Storage) Quotes() []model.Quote {
+	return *ims.wow.Load()
 }
 
-func (ims Storage) GetRandomWOW(ctx context.Context) string {
-	return ims.wow[rand.Intn(len(ims.wow))]
+func (ims *Storage) Len() int {
+	return len(*ims.wow.Load())
 }
@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/pullya/wow_tcp_server/tcp-server/internal/model"
+)
+
+func TestStorage_GetRandomQuote(t *testing.T) {
+	t.Parallel()
+
+	s := New([]model.Quote{
+		{ID: "1", Text: "Luck is what happens when preparation meets opportunity", Author: "Seneca", Language: "en", Tags: []string{"luck"}},
+		{ID: "2", Text: "Vivere est cogitare", Author: "Cicero", Language: "la", Tags: []string{"life", "thought"}},
+		{ID: "3", Text: "While we wait for life, life passes", Author: "Seneca", Language: "en", Tags: []string{"life", "time"}},
+	})
+
+	tests := []struct {
+		name    string
+		filter  model.QuoteFilter
+		wantIDs []string
+		wantErr error
+	}{
+		{
+			name:    "No filter",
+			filter:  model.QuoteFilter{},
+			wantIDs: []string{"1", "2", "3"},
+		},
+		{
+			name:    "Author",
+			filter:  model.QuoteFilter{Author: "seneca"},
+			wantIDs: []string{"1", "3"},
+		},
+		{
+			name:    "Language and tag",
+			filter:  model.QuoteFilter{Language: "EN", Tags: []string{"life"}},
+			wantIDs: []string{"3"},
+		},
+		{
+			name:    "All tags required",
+			filter:  model.QuoteFilter{Tags: []string{"life", "thought"}},
+			wantIDs: []string{"2"},
+		},
+		{
+			name:    "No match",
+			filter:  model.QuoteFilter{Author: "Cicero", Language: "en"},
+			wantErr: ErrNoMatchingQuote,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			seen := make(map[string]bool)
+			for i := 0; i < 200; i++ {
+				q, err := s.GetRandomQuote(context.Background(), tt.filter)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Storage.GetRandomQuote() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil {
+					return
+				}
+				seen[q.ID] = true
+			}
+
+			if len(seen) != len(tt.wantIDs) {
+				t.Errorf("Storage.GetRandomQuote() returned ids %v, want %v", seen, tt.wantIDs)
+			}
+			for _, id := range tt.wantIDs {
+				if !seen[id] {
+					t.Errorf("Storage.GetRandomQuote() never returned id %s", id)
+				}
+			}
+		})
+	}
+}
+
+func TestStorage_GetDailyQuote(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	corpus := testCorpus(10)
+	reversed := make([]model.Quote, len(corpus))
+	for i, q := range corpus {
+		reversed[len(corpus)-1-i] = q
+	}
+	s, replica := New(corpus), New(reversed)
+
+	picked := make(map[string]bool)
+	for day := 1; day <= 30; day++ {
+		date := fmt.Sprintf("2024-04-%02d", day)
+
+		q, err := s.GetDailyQuote(ctx, date)
+		if err != nil {
+			t.Fatalf("Storage.GetDailyQuote() error = %v", err)
+		}
+		again, _ := s.GetDailyQuote(ctx, date)
+		other, _ := replica.GetDailyQuote(ctx, date)
+		if q.ID != again.ID || q.ID != other.ID {
+			t.Fatalf("Storage.GetDailyQuote(%s) = %s, %s, %s; want the same quote", date, q.ID, again.ID, other.ID)
+		}
+		picked[q.ID] = true
+	}
+
+	if len(picked) < 5 {
+		t.Errorf("Storage.GetDailyQuote() picked only %d different quotes in 30 days", len(picked))
+	}
+}
+
+func TestStorage_AddDeleteQuote(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	s := New([]model.Quote{{ID: "1", Text: "Vivere est cogitare"}})
+	before := s.Quotes()
+
+	added, err := s.AddQuote(ctx, model.Quote{Text: "Carpe diem "})
+	if err != nil {
+		t.Fatalf("Storage.AddQuote() error = %v", err)
+	}
+	if added.ID != QuoteID("Carpe diem") {
+		t.Errorf("Storage.AddQuote() id = %s, want generated from text", added.ID)
+	}
+
+	tests := []struct {
+		name    string
+		quote   model.Quote
+		wantErr error
+	}{
+		{
+			name:    "Duplicate text",
+			quote:   model.Quote{Text: "CARPE DIEM"},
+			wantErr: ErrQuoteExists,
+		},
+		{
+			name:    "Duplicate id",
+			quote:   model.Quote{ID: "1", Text: "Something else"},
+			wantErr: ErrQuoteExists,
+		},
+		{
+			name:    "Empty text",
+			quote:   model.Quote{Text: ""},
+			wantErr: ErrInvalidQuote,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.AddQuote(ctx, tt.quote); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Storage.AddQuote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := s.DeleteQuote(ctx, "1"); err != nil {
+		t.Fatalf("Storage.DeleteQuote() error = %v", err)
+	}
+	if err := s.DeleteQuote(ctx, "1"); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("Storage.DeleteQuote() error = %v, want %v", err, ErrQuoteNotFound)
+	}
+
+	if quotes, _ := s.ListQuotes(ctx); len(quotes) != 1 || quotes[0].ID != added.ID {
+		t.Errorf("Storage.ListQuotes() = %v, want only %s", quotes, added.ID)
+	}
+	if len(before) != 1 || before[0].ID != "1" {
+		t.Errorf("previously loaded corpus changed to %v", before)
+	}
+
+	if err := s.DeleteQuote(ctx, added.ID); err != nil {
+		t.Fatalf("Storage.DeleteQuote() error = %v", err)
+	}
+	if _, err := s.GetRandomQuote(ctx, model.QuoteFilter{}); !errors.Is(err, ErrNoMatchingQuote) {
+		t.Errorf("Storage.GetRandomQuote() error = %v, want %v for an empty corpus", err, ErrNoMatchingQuote)
+	}
+}
+
+func TestStorage_UpdateQuote(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	s := New([]model.Quote{{ID: "1", Text: "Vivere est cogitare"}, {ID: "2", Text: "Carpe diem"}})
+	before := s.Quotes()
+
+	tests := []struct {
+		name    string
+		quote   model.Quote
+		want    model.Quote
+		wantErr error
+	}{
+		{
+			name:  "Success",
+			quote: model.Quote{ID: "1", Text: " Vivere est cogitare", Author: "Cicero"},
+			want:  model.Quote{ID: "1", Text: "Vivere est cogitare", Author: "Cicero"},
+		},
+		{
+			name:    "Text of another quote",
+			quote:   model.Quote{ID: "1", Text: "carpe diem"},
+			wantErr: ErrQuoteExists,
+		},
+		{
+			name:    "Unknown id",
+			quote:   model.Quote{ID: "3", Text: "Something else"},
+			wantErr: ErrQuoteNotFound,
+		},
+		{
+			name:    "Empty id",
+			quote:   model.Quote{Text: "Something else"},
+			wantErr: ErrInvalidQuote,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.UpdateQuote(ctx, tt.quote)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Storage.UpdateQuote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Storage.UpdateQuote() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got, err := s.GetQuote(ctx, "1"); err != nil || got.Author != "Cicero" {
+		t.Errorf("Storage.GetQuote() = %v, %v, want the updated quote", got, err)
+	}
+	if _, err := s.GetQuote(ctx, "3"); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("Storage.GetQuote() error = %v, want %v", err, ErrQuoteNotFound)
+	}
+	if before[0].Author != "" {
+		t.Errorf("previously loaded corpus changed to %v", before)
+	}
+}
@@ -0,0 +1,110 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMalformed       = errors.New("malformed challenge token")
+	ErrInvalidSign     = errors.New("invalid challenge signature")
+	ErrExpired         = errors.New("challenge token expired")
+	ErrAddressMismatch = errors.New("challenge issued for another address")
+)
+
+// Claims is the challenge state carried inside a signed token.
+type Claims struct {
+	ID         string `json:"id"`
+	IssuedAt   int64  `json:"iat"`
+	Difficulty int    `json:"diff"`
+	Address    string `json:"addr"`
+	Payload    string `json:"pl,omitempty"`
+}
+
+// Signer issues and verifies HMAC-signed challenge tokens, so any server
+// sharing the secret can check a solution without a shared request store.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func New(secret []byte, ttl time.Duration) *Signer {
+	return &Signer{
+		secret: secret,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// Issue signs a new challenge bound to address. The payload is opaque to the
+// signer and is returned in Claims by Verify.
+func (s *Signer) Issue(address string, difficulty int, payload string) (string, error) {
+	claims := Claims{
+		ID:         uuid.New().String(),
+		IssuedAt:   s.now().Unix(),
+		Difficulty: difficulty,
+		Address:    address,
+		Payload:    payload,
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded)), nil
+}
+
+func (s *Signer) Verify(token string, address string) (Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, ErrMalformed
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return Claims{}, ErrMalformed
+	}
+	if !hmac.Equal(mac, s.sign(encoded)) {
+		return Claims{}, ErrInvalidSign
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	if s.now().After(claims.ExpiresAt(s.ttl)) {
+		return Claims{}, ErrExpired
+	}
+	if claims.Address != address {
+		return Claims{}, ErrAddressMismatch
+	}
+
+	return claims, nil
+}
+
+func (c Claims) ExpiresAt(ttl time.Duration) time.Time {
+	return time.Unix(c.IssuedAt, 0).Add(ttl)
+}
+
+func (s *Signer) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
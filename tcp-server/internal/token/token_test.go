@@ -0,0 +1,100 @@
+package token
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSigner_Verify(t *testing.T) {
+	t.Parallel()
+
+	issuedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ttl := time.Minute
+
+	issuer := New([]byte("secret"), ttl)
+	issuer.now = func() time.Time { return issuedAt }
+
+	valid, err := issuer.Issue("10.0.0.1", 20, `{"tags":["life"]}`)
+	if err != nil {
+		t.Fatalf("Signer.Issue() error = %v", err)
+	}
+	payload, _, _ := strings.Cut(valid, ".")
+
+	tests := []struct {
+		name    string
+		secret  string
+		now     time.Time
+		token   string
+		address string
+		wantErr error
+	}{
+		{
+			name:    "Success",
+			secret:  "secret",
+			now:     issuedAt.Add(30 * time.Second),
+			token:   valid,
+			address: "10.0.0.1",
+			wantErr: nil,
+		},
+		{
+			name:    "Expired",
+			secret:  "secret",
+			now:     issuedAt.Add(ttl + time.Second),
+			token:   valid,
+			address: "10.0.0.1",
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "Another address",
+			secret:  "secret",
+			now:     issuedAt,
+			token:   valid,
+			address: "10.0.0.2",
+			wantErr: ErrAddressMismatch,
+		},
+		{
+			name:    "Another secret",
+			secret:  "other",
+			now:     issuedAt,
+			token:   valid,
+			address: "10.0.0.1",
+			wantErr: ErrInvalidSign,
+		},
+		{
+			name:    "Tampered payload",
+			secret:  "secret",
+			now:     issuedAt,
+			token:   payload + "x." + strings.SplitN(valid, ".", 2)[1],
+			address: "10.0.0.1",
+			wantErr: ErrInvalidSign,
+		},
+		{
+			name:    "No signature",
+			secret:  "secret",
+			now:     issuedAt,
+			token:   payload,
+			address: "10.0.0.1",
+			wantErr: ErrMalformed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New([]byte(tt.secret), ttl)
+			s.now = func() time.Time { return tt.now }
+
+			claims, err := s.Verify(tt.token, tt.address)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Signer.Verify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && claims.Difficulty != 20 {
+				t.Errorf("Signer.Verify() difficulty = %v, want %v", claims.Difficulty, 20)
+			}
+			if err == nil && claims.Payload != `{"tags":["life"]}` {
+				t.Errorf("Signer.Verify() payload = %v, want %v", claims.Payload, `{"tags":["life"]}`)
+			}
+		})
+	}
+}